@@ -0,0 +1,210 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// GenerateClient renders the typed client methods for Operations as the
+// source of client/zz_generated.go (see cmd/apigen).
+func GenerateClient() ([]byte, error) {
+	g := &clientGen{imports: map[string]bool{"context": true}}
+
+	var body bytes.Buffer
+	for _, op := range Operations {
+		if op.ID == "OpenAPI" {
+			continue
+		}
+		g.operation(&body, op)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by cmd/apigen from api.Operations; DO NOT EDIT.\n\n")
+	out.WriteString("package client\n\nimport (\n")
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	// standard library first, then module packages
+	sort.SliceStable(paths, func(i, j int) bool {
+		return !strings.Contains(paths[i], ".") && strings.Contains(paths[j], ".")
+	})
+	for i, p := range paths {
+		if i > 0 && strings.Contains(p, ".") && !strings.Contains(paths[i-1], ".") {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "\t%q\n", p)
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated client: %w\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+type clientGen struct {
+	imports map[string]bool
+}
+
+func (g *clientGen) operation(w *bytes.Buffer, op Operation) {
+	args := []string{"ctx context.Context"}
+	call := []string{"ctx", fmt.Sprintf("%q", op.Method)}
+
+	// path
+	var parts []string
+	lit := ""
+	for _, seg := range strings.Split(op.Path, "/")[1:] {
+		if !strings.HasPrefix(seg, ":") {
+			lit += "/" + seg
+			continue
+		}
+		arg := g.argName(seg[1:])
+		args = append(args, arg+" string")
+		parts = append(parts, fmt.Sprintf("%q", lit+"/"), "url.PathEscape("+arg+")")
+		lit = ""
+		g.imports["net/url"] = true
+	}
+	if lit != "" {
+		parts = append(parts, fmt.Sprintf("%q", lit))
+	}
+	call = append(call, strings.Join(parts, " + "))
+
+	// query
+	query := "nil"
+	if len(op.Query) > 0 {
+		params := op.ID + "Params"
+		g.params(w, params, op)
+		args = append(args, "params "+params)
+		query = "params.values()"
+	}
+	call = append(call, query)
+
+	// body
+	if op.Request != nil {
+		args = append(args, "body "+g.typeExpr(reflect.TypeOf(op.Request)))
+		call = append(call, "body")
+	} else {
+		call = append(call, "nil")
+	}
+
+	fmt.Fprintf(w, "\n// %s: %s (%s %s).\n", op.ID, op.Summary, op.Method, op.Path)
+	if op.Response == nil {
+		g.imports["net/http"] = true
+		fmt.Fprintf(w, "// The caller must close the response body.\n")
+		fmt.Fprintf(w, "func (c *Client) %s(%s) (*http.Response, error) {\n", op.ID, strings.Join(args, ", "))
+		fmt.Fprintf(w, "\treturn c.raw(%s)\n}\n", strings.Join(call, ", "))
+		return
+	}
+
+	t := reflect.TypeOf(op.Response)
+	ret := g.typeExpr(t)
+	if t.Kind() == reflect.Struct {
+		ret = "*" + ret
+	}
+	fmt.Fprintf(w, "func (c *Client) %s(%s) (%s, error) {\n", op.ID, strings.Join(args, ", "), ret)
+	fmt.Fprintf(w, "\tvar out %s\n", g.typeExpr(t))
+	fmt.Fprintf(w, "\tif err := c.do(%s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", strings.Join(call, ", "))
+	if t.Kind() == reflect.Struct {
+		fmt.Fprintf(w, "\treturn &out, nil\n}\n")
+	} else {
+		fmt.Fprintf(w, "\treturn out, nil\n}\n")
+	}
+}
+
+func (g *clientGen) params(w *bytes.Buffer, name string, op Operation) {
+	fmt.Fprintf(w, "\n// %s are the query parameters of %s. Zero values are not sent.\n", name, op.ID)
+	fmt.Fprintf(w, "type %s struct {\n", name)
+	for _, p := range op.Query {
+		fmt.Fprintf(w, "\t%s %s // %s\n", fieldName(p.Name), g.paramType(p.Type), p.Description)
+	}
+	fmt.Fprintf(w, "}\n\nfunc (p %s) values() url.Values {\n\tq := url.Values{}\n", name)
+	g.imports["net/url"] = true
+	for _, p := range op.Query {
+		f := "p." + fieldName(p.Name)
+		switch p.Type {
+		case "date-time":
+			fmt.Fprintf(w, "\tif !%s.IsZero() {\n\t\tq.Set(%q, %s.UTC().Format(time.RFC3339))\n\t}\n", f, p.Name, f)
+		case "integer":
+			g.imports["strconv"] = true
+			fmt.Fprintf(w, "\tif %s != 0 {\n\t\tq.Set(%q, strconv.Itoa(%s))\n\t}\n", f, p.Name, f)
+		case "number":
+			g.imports["strconv"] = true
+			fmt.Fprintf(w, "\tif %s != 0 {\n\t\tq.Set(%q, strconv.FormatFloat(%s, 'f', -1, 64))\n\t}\n", f, p.Name, f)
+		default:
+			fmt.Fprintf(w, "\tif %s != \"\" {\n\t\tq.Set(%q, %s)\n\t}\n", f, p.Name, f)
+		}
+	}
+	fmt.Fprintf(w, "\treturn q\n}\n")
+}
+
+func (g *clientGen) paramType(typ string) string {
+	switch typ {
+	case "date-time":
+		g.imports["time"] = true
+		return "time.Time"
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	}
+	return "string"
+}
+
+// typeExpr returns the Go expression for t as seen from package client,
+// recording the import it needs.
+func (g *clientGen) typeExpr(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return "*" + g.typeExpr(t.Elem())
+	case reflect.Slice:
+		return "[]" + g.typeExpr(t.Elem())
+	case reflect.Map:
+		return "map[" + g.typeExpr(t.Key()) + "]" + g.typeExpr(t.Elem())
+	}
+	if t.PkgPath() == "" {
+		return t.String()
+	}
+	g.imports[t.PkgPath()] = true
+	return t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:] + "." + t.Name()
+}
+
+// argName turns a path parameter into a Go argument name, avoiding names of
+// imported packages (e.g. the :queue parameter vs package queue).
+func (g *clientGen) argName(p string) string {
+	for _, op := range Operations {
+		for _, v := range []any{op.Request, op.Response, op.Alt} {
+			if v == nil {
+				continue
+			}
+			t := reflect.TypeOf(v)
+			for t.Kind() == reflect.Slice || t.Kind() == reflect.Pointer {
+				t = t.Elem()
+			}
+			if strings.HasSuffix(t.PkgPath(), "/"+p) {
+				return p + "Name"
+			}
+		}
+	}
+	if p == "id" {
+		return p
+	}
+	n := fieldName(p)
+	return strings.ToLower(n[:1]) + n[1:]
+}
+
+// fieldName turns a query parameter into an exported Go name: deviceId -> DeviceID.
+func fieldName(p string) string {
+	n := strings.ToUpper(p[:1]) + p[1:]
+	if strings.HasSuffix(n, "Id") {
+		n = strings.TrimSuffix(n, "Id") + "ID"
+	}
+	return n
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	Title   = "Sound-based forest monitoring - EC2 worker API"
+	Version = "1.0.0"
+)
+
+var (
+	specOnce sync.Once
+	specJSON []byte
+)
+
+// ServeSpec serves the OpenAPI document at GET /openapi.json.
+func ServeSpec(w http.ResponseWriter, r *http.Request) {
+	specOnce.Do(func() {
+		specJSON, _ = json.MarshalIndent(Document(), "", "  ")
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(specJSON)
+}
+
+// Document builds the OpenAPI 3 document from Operations, deriving schemas
+// from the Go types by reflection (json tags, omitempty/omitzero = optional).
+func Document() map[string]any {
+	sb := newSchemaBuilder()
+	errRef := sb.schema(reflect.TypeOf(Error{}))
+
+	paths := map[string]map[string]any{}
+	for _, op := range Operations {
+		path := OpenAPIPath(op.Path)
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+
+		params := []any{}
+		for _, p := range PathParams(op.Path) {
+			params = append(params, map[string]any{
+				"name": p, "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+			})
+		}
+		for _, p := range op.Query {
+			params = append(params, map[string]any{
+				"name": p.Name, "in": "query", "description": p.Description, "schema": paramSchema(p.Type),
+			})
+		}
+
+		ok := map[string]any{"description": "OK"}
+		switch {
+		case op.Response != nil:
+			s := sb.schema(reflect.TypeOf(op.Response))
+			if op.Alt != nil {
+				s = map[string]any{"oneOf": []any{s, sb.schema(reflect.TypeOf(op.Alt))}}
+			}
+			ok["content"] = map[string]any{"application/json": map[string]any{"schema": s}}
+		case op.Produces != "":
+			ok["content"] = map[string]any{op.Produces: map[string]any{
+				"schema": map[string]any{"type": "string", "format": "binary"},
+			}}
+		}
+
+		o := map[string]any{
+			"operationId": op.ID,
+			"summary":     op.Summary,
+			"tags":        []string{op.Tag},
+			"parameters":  params,
+			"responses": map[string]any{
+				"200": ok,
+				"default": map[string]any{
+					"description": "Error",
+					"content":     map[string]any{"application/json": map[string]any{"schema": errRef}},
+				},
+			},
+		}
+		if op.Role != "" {
+			o["security"] = []any{map[string]any{"bearerAuth": []string{}}}
+			o["x-required-role"] = op.Role
+		}
+		if op.Request != nil {
+			o["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{"application/json": map[string]any{
+					"schema": sb.schema(reflect.TypeOf(op.Request)),
+				}},
+			}
+		}
+		paths[path][strings.ToLower(op.Method)] = o
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": Title, "version": Version},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": sb.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+// OpenAPIPath converts gin path syntax (/alerts/:deviceId) to OpenAPI (/alerts/{deviceId}).
+func OpenAPIPath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, s := range parts {
+		if strings.HasPrefix(s, ":") {
+			parts[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// PathParams returns the names of the :params of a gin path, in order.
+func PathParams(p string) []string {
+	var res []string
+	for _, s := range strings.Split(p, "/") {
+		if strings.HasPrefix(s, ":") {
+			res = append(res, s[1:])
+		}
+	}
+	return res
+}
+
+func paramSchema(typ string) map[string]any {
+	if typ == "date-time" {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	return map[string]any{"type": typ}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type schemaBuilder struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{components: map[string]any{}, names: map[reflect.Type]string{}}
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == reflect.TypeOf(time.Duration(0)):
+		return map[string]any{"type": "integer", "format": "int64"}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + b.component(t)}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Interface:
+		return map[string]any{}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+// component registers a named struct under components/schemas. Types with
+// the same name from different packages get the package name as prefix.
+func (b *schemaBuilder) component(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	for other, n := range b.names {
+		if n == name && other != t {
+			pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+			name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+			break
+		}
+	}
+	b.names[t] = name
+	b.components[name] = map[string]any{} // placeholder for recursive types
+	b.components[name] = b.structSchema(t)
+	return name
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	required := []string{}
+	b.addFields(t, props, &required)
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func (b *schemaBuilder) addFields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			b.addFields(f.Type, props, required)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = b.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+			*required = append(*required, name)
+		}
+	}
+}
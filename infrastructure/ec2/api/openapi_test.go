@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSchemaFromStruct(t *testing.T) {
+	type inner struct {
+		At time.Time `json:"at"`
+	}
+	type sample struct {
+		Name   string            `json:"name"`
+		Count  int               `json:"count,omitempty"`
+		Tags   []string          `json:"tags"`
+		Meta   map[string]string `json:"meta,omitempty"`
+		Inner  *inner            `json:"inner,omitzero"`
+		Hidden string            `json:"-"`
+		secret string
+	}
+
+	sb := newSchemaBuilder()
+	ref := sb.schema(reflect.TypeOf(sample{}))
+	if ref["$ref"] != "#/components/schemas/sample" {
+		t.Fatalf("ref = %v", ref)
+	}
+	s := sb.components["sample"].(map[string]any)
+	props := s["properties"].(map[string]any)
+	if len(props) != 5 {
+		t.Fatalf("properties = %v", props)
+	}
+	if got := s["required"]; !reflect.DeepEqual(got, []string{"name", "tags"}) {
+		t.Fatalf("required = %v", got)
+	}
+	at := sb.components["inner"].(map[string]any)["properties"].(map[string]any)["at"]
+	if !reflect.DeepEqual(at, map[string]any{"type": "string", "format": "date-time"}) {
+		t.Fatalf("time schema = %v", at)
+	}
+}
+
+func TestDocument(t *testing.T) {
+	doc := Document()
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := parsed.Paths["/alerts/{deviceId}/{ts}"]["patch"]; !ok {
+		t.Fatal("PATCH /alerts/{deviceId}/{ts} missing")
+	}
+	for _, name := range []string{"SourceList", "StoredSourceList", "AlertList", "Alert", "SourceGroup", "Error"} {
+		if _, ok := parsed.Components.Schemas[name]; !ok {
+			t.Errorf("schema %s missing", name)
+		}
+	}
+
+	ids := map[string]bool{}
+	for _, op := range Operations {
+		if ids[op.ID] {
+			t.Errorf("duplicate operation id %s", op.ID)
+		}
+		ids[op.ID] = true
+	}
+}
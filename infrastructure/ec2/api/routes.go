@@ -0,0 +1,127 @@
+package api
+
+import (
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/auth"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/export"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/queue"
+)
+
+// Param is a query parameter. Type is one of string, integer, number or
+// date-time (RFC3339 string).
+type Param struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// Operation describes one route of router.SetupRouter. The router test checks
+// that both stay in sync.
+type Operation struct {
+	ID      string // operationId and client method name
+	Method  string
+	Path    string // gin syntax, e.g. /alerts/:deviceId/:ts
+	Tag     string
+	Summary string
+	// minimum role (auth.Role*), empty for public routes
+	Role  string
+	Query []Param
+	// JSON request body, nil for none
+	Request any
+	// JSON 200 body; Alt is an alternative shape of the same response (oneOf)
+	Response any
+	Alt      any
+	// content type of a non-JSON response (Response is then nil)
+	Produces string
+}
+
+var (
+	timeWindow = []Param{
+		{"from", "date-time", "start of the time window"},
+		{"to", "date-time", "end of the time window"},
+	}
+	alertFilters = append(timeWindow[:len(timeWindow):len(timeWindow)],
+		Param{"deviceId", "string", "single sensor"},
+		Param{"status", "string", "comma separated statuses"},
+		Param{"class", "string", "comma separated sound classes"},
+		Param{"bbox", "string", "minLon,minLat,maxLon,maxLat"},
+		Param{"limit", "integer", "page size, max 500"},
+		Param{"cursor", "string", "nextCursor of the previous page"},
+	)
+	sourceFilters = append(timeWindow[:len(timeWindow):len(timeWindow)],
+		Param{"state", "string", "active, expired or all; reads the source table"},
+	)
+	exportFilters = append(sourceFilters[:len(sourceFilters):len(sourceFilters)],
+		Param{"zone", "string", "configured zone name"},
+		Param{"bbox", "string", "minLon,minLat,maxLon,maxLat"},
+	)
+)
+
+var Operations = []Operation{
+	{ID: "Health", Method: "GET", Path: "/health", Tag: "system", Role: auth.RoleAdmin, Summary: "Circuit breaker state of AWS dependencies with liveness and readiness details",
+		Response: Health{}},
+	{ID: "Liveness", Method: "GET", Path: "/healthz", Tag: "system", Summary: "Liveness probe (status only); 503 when the consumer loop is stalled",
+		Response: Probe{}},
+	{ID: "Readiness", Method: "GET", Path: "/readyz", Tag: "system", Summary: "Readiness probe (status only, checks cached briefly); 503 when not ready",
+		Response: Probe{}},
+	{ID: "Metrics", Method: "GET", Path: "/metrics", Tag: "system", Role: auth.RoleAdmin, Summary: "Prometheus metrics of the queue consumer, alert pipeline and HTTP API",
+		Produces: "text/plain"},
+	{ID: "Stream", Method: "GET", Path: "/stream", Tag: "events", Role: auth.RoleViewer, Summary: "Server-Sent Events with new alerts and source changes",
+		Query:    []Param{{"types", "string", "comma separated event types"}, {"lastEventId", "string", "resume after this event"}},
+		Produces: "text/event-stream"},
+
+	{ID: "ListSensors", Method: "GET", Path: "/sensors", Tag: "sensors", Role: auth.RoleViewer, Summary: "Registered sensors with computed online/stale/offline status",
+		Query:    []Param{{"status", "string", "online, stale or offline"}},
+		Response: []models.Sensor{}},
+	{ID: "ListSensorsGeoJSON", Method: "GET", Path: "/sensors.geojson", Tag: "sensors", Role: auth.RoleViewer, Summary: "Registered sensors as GeoJSON",
+		Query:    []Param{{"status", "string", "online, stale or offline"}},
+		Response: export.FeatureCollection{}},
+	{ID: "SensorTelemetry", Method: "GET", Path: "/sensors/:id/telemetry", Tag: "sensors", Role: auth.RoleViewer, Summary: "Telemetry of one sensor, downsampled",
+		Query: []Param{
+			{"from", "string", "RFC3339, default to-24h"},
+			{"to", "string", "RFC3339, default now"},
+			{"step", "string", "bucket duration (e.g. 15m) or raw; automatic when empty"},
+		},
+		Response: TelemetrySeries{}},
+
+	{ID: "ListSources", Method: "GET", Path: "/sources", Tag: "sources", Role: auth.RoleViewer, Summary: "Localized sound sources",
+		Query: sourceFilters, Response: SourceList{}, Alt: StoredSourceList{}},
+	{ID: "ListSourcesGeoJSON", Method: "GET", Path: "/sources.geojson", Tag: "sources", Role: auth.RoleViewer, Summary: "Localized sound sources as GeoJSON",
+		Query: sourceFilters, Response: export.FeatureCollection{}},
+	{ID: "ExportSourcesKML", Method: "GET", Path: "/sources.kml", Tag: "sources", Role: auth.RoleViewer, Summary: "Sources as KML for Google Earth",
+		Query: exportFilters, Produces: export.KMLContentType},
+	{ID: "ExportSourcesGPX", Method: "GET", Path: "/sources.gpx", Tag: "sources", Role: auth.RoleViewer, Summary: "Sources as GPX waypoints",
+		Query: exportFilters, Produces: export.GPXContentType},
+	{ID: "GetSource", Method: "GET", Path: "/sources/:id", Tag: "sources", Role: auth.RoleViewer, Summary: "One source, live or stored",
+		Response: processor.SourceGroup{}},
+	{ID: "GetSourceMap", Method: "GET", Path: "/sources/:id/map.png", Tag: "sources", Role: auth.RoleViewer, Summary: "PNG map of a source and its alerts",
+		Query: []Param{{"size", "integer", "longest side in pixels"}}, Produces: "image/png"},
+
+	{ID: "ListAlerts", Method: "GET", Path: "/alerts", Tag: "alerts", Role: auth.RoleViewer, Summary: "Alerts in a time window, cursor paginated",
+		Query: alertFilters, Response: AlertList{}},
+	{ID: "ListAlertsGeoJSON", Method: "GET", Path: "/alerts.geojson", Tag: "alerts", Role: auth.RoleViewer, Summary: "Alerts as GeoJSON",
+		Query: alertFilters, Response: export.FeatureCollection{}},
+	{ID: "GetAlert", Method: "GET", Path: "/alerts/:deviceId/:ts", Tag: "alerts", Role: auth.RoleViewer, Summary: "Alert detail with media links",
+		Response: AlertDetail{}},
+	{ID: "UpdateAlertStatus", Method: "PATCH", Path: "/alerts/:deviceId/:ts", Tag: "alerts", Role: auth.RoleRanger, Summary: "Change the workflow status of an alert",
+		Request: StatusUpdate{}, Response: models.Alert{}},
+	{ID: "GetAlertAudio", Method: "GET", Path: "/alerts/:deviceId/:ts/audio", Tag: "alerts", Role: auth.RoleRanger, Summary: "Audio clip, supports Range requests",
+		Produces: "audio/wav"},
+	{ID: "GetAlertMedia", Method: "GET", Path: "/alerts/:deviceId/:ts/media/:kind", Tag: "alerts", Role: auth.RoleRanger, Summary: "Media derived from the audio clip, e.g. spectrogram.png",
+		Produces: "application/octet-stream"},
+
+	{ID: "ListQueueMessages", Method: "GET", Path: "/admin/queues/:queue/messages", Tag: "admin", Role: auth.RoleAdmin, Summary: "Peek at DLQ or quarantine messages",
+		Query: []Param{{"max", "integer", "1-100"}}, Response: DeadLetterList{}},
+	{ID: "PurgeQueue", Method: "DELETE", Path: "/admin/queues/:queue/messages", Tag: "admin", Role: auth.RoleAdmin, Summary: "Purge the whole queue",
+		Response: Purged{}},
+	{ID: "GetQueueMessage", Method: "GET", Path: "/admin/queues/:queue/messages/:id", Tag: "admin", Role: auth.RoleAdmin, Summary: "One queued message",
+		Response: queue.DeadLetter{}},
+	{ID: "RedriveQueueMessage", Method: "POST", Path: "/admin/queues/:queue/messages/:id/redrive", Tag: "admin", Role: auth.RoleAdmin, Summary: "Send a message back to the alerts queue",
+		Response: Redriven{}},
+	{ID: "PurgeQueueMessage", Method: "DELETE", Path: "/admin/queues/:queue/messages/:id", Tag: "admin", Role: auth.RoleAdmin, Summary: "Delete one message",
+		Response: Purged{}},
+
+	{ID: "OpenAPI", Method: "GET", Path: "/openapi.json", Tag: "system", Summary: "This document",
+		Produces: "application/json"},
+}
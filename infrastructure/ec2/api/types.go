@@ -0,0 +1,131 @@
+// Package api holds the request and response types of the EC2 worker API, the
+// route table and the OpenAPI document built from both.
+package api
+
+import (
+	"time"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/edge"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/queue"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/resilience"
+)
+
+// Error is the body of every 4xx/5xx JSON response.
+type Error struct {
+	Error string `json:"error"`
+}
+
+// SourceList is returned by GET /sources (live view).
+type SourceList struct {
+	Count   int                     `json:"count"`
+	Sources []processor.SourceGroup `json:"sources"`
+}
+
+// StoredSourceList is returned by GET /sources?state=... (source table).
+type StoredSourceList struct {
+	Count   int             `json:"count"`
+	Sources []models.Source `json:"sources"`
+}
+
+type AlertList struct {
+	Count      int            `json:"count"`
+	Alerts     []models.Alert `json:"alerts"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
+
+type MediaLink struct {
+	Key  string `json:"key"`
+	Kind string `json:"kind"`
+	URL  string `json:"url,omitempty"`
+	Size int64  `json:"size,omitempty"`
+}
+
+type AlertDetail struct {
+	Alert   models.Alert            `json:"alert"`
+	Audio   *MediaLink              `json:"audio,omitempty"`
+	Derived []MediaLink             `json:"derived"`
+	Sources []processor.SourceGroup `json:"sources"`
+}
+
+// StatusUpdate is the body of PATCH /alerts/:deviceId/:ts.
+type StatusUpdate struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// Health is returned by GET /health (admin): breakers plus the details behind
+// the two probes.
+type Health struct {
+	Status    string              `json:"status"`
+	Breakers  []resilience.Status `json:"breakers"`
+	Liveness  Liveness            `json:"liveness"`
+	Readiness Readiness           `json:"readiness"`
+}
+
+// Probe is returned by the public GET /healthz and GET /readyz (503 when the
+// probe fails); the details are only in GET /health.
+type Probe struct {
+	Status string `json:"status"`
+}
+
+type DeadLetterList struct {
+	Count    int                `json:"count"`
+	Messages []queue.DeadLetter `json:"messages"`
+}
+
+type Redriven struct {
+	Redriven string `json:"redriven"`
+}
+
+type Purged struct {
+	Purged string `json:"purged"`
+}
+
+// Liveness is the detail behind GET /healthz.
+type Liveness struct {
+	Status    string    `json:"status"`
+	StartedAt time.Time `json:"startedAt"`
+	// time since the consumer loop last iterated
+	LoopAge string `json:"loopAge,omitempty"`
+}
+
+// Readiness is the detail behind GET /readyz (not ready when Status is "not_ready").
+type Readiness struct {
+	Status   string         `json:"status"`
+	Reasons  []string       `json:"reasons,omitempty"`
+	Checks   []CheckResult  `json:"checks"`
+	Queue    *queue.Backlog `json:"queue,omitempty"`
+	DLQ      *queue.Backlog `json:"dlq,omitempty"`
+	Consumer queue.Activity `json:"consumer"`
+	// time since the last successfully processed message
+	SinceLastProcessed string      `json:"sinceLastProcessed,omitempty"`
+	Memory             MemoryStats `json:"memory"`
+	// upload state of an edge gateway (informational, never makes it unready)
+	Sync *edge.SyncStatus `json:"sync,omitempty"`
+}
+
+type CheckResult struct {
+	Name      string `json:"name"`
+	OK        bool   `json:"ok"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+type MemoryStats struct {
+	Alerts  int `json:"alerts"`
+	Sources int `json:"sources"`
+}
+
+// TelemetrySeries is returned by GET /sensors/:id/telemetry.
+type TelemetrySeries struct {
+	DeviceID string    `json:"deviceId"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	// bucket size, empty for raw samples
+	Step string `json:"step,omitempty"`
+	// raw samples in the range
+	Samples int                `json:"samples"`
+	Points  []models.Telemetry `json:"points"`
+}
@@ -0,0 +1,196 @@
+// Package auth authenticates operators with JWT bearer tokens verified
+// against a JWKS, and enforces role-based access on the gin routes.
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Roles are ordered: every role includes the permissions of the ones below.
+const (
+	RoleViewer = "viewer" // read alerts, sources, sensors, maps
+	RoleRanger = "ranger" // + change alert status, listen to audio
+	RoleAdmin  = "admin"  // + sensor edits, DLQ/quarantine admin
+)
+
+var roleRank = map[string]int{RoleViewer: 1, RoleRanger: 2, RoleAdmin: 3}
+
+const principalKey = "auth.principal"
+
+type Principal struct {
+	Subject string   `json:"sub"`
+	Name    string   `json:"name"`
+	Roles   []string `json:"roles"`
+}
+
+// Has reports whether the principal holds role or a higher one.
+func (p *Principal) Has(role string) bool {
+	if p == nil {
+		return false
+	}
+	need := roleRank[role]
+	for _, r := range p.Roles {
+		if roleRank[r] >= need && need > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type Options struct {
+	Issuer   string
+	Audience string
+	// claim holding the roles, dotted for nested claims (e.g. realm_access.roles)
+	RolesClaim string
+}
+
+type Authenticator struct {
+	keys   *KeySet
+	opts   Options
+	parser *jwt.Parser
+}
+
+func New(keys *KeySet, opts Options) *Authenticator {
+	if opts.RolesClaim == "" {
+		opts.RolesClaim = "roles"
+	}
+	popts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if opts.Issuer != "" {
+		popts = append(popts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		popts = append(popts, jwt.WithAudience(opts.Audience))
+	}
+	return &Authenticator{keys: keys, opts: opts, parser: jwt.NewParser(popts...)}
+}
+
+// Disabled returns an authenticator that treats every request as an admin
+// named by the X-Operator header. Only for local development.
+func Disabled() *Authenticator {
+	return &Authenticator{}
+}
+
+func (a *Authenticator) Enabled() bool {
+	return a != nil && a.keys != nil
+}
+
+// Authenticate verifies the bearer token, if any, and stores the principal on
+// the context. Requests without a token pass through; Require rejects them.
+// Browsers cannot set headers on <audio> and EventSource, so GET requests may
+// also carry the token in ?access_token=.
+func (a *Authenticator) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.Enabled() {
+			name := c.GetHeader("X-Operator")
+			if name == "" {
+				name = "anonymous"
+			}
+			c.Set(principalKey, &Principal{Subject: name, Name: name, Roles: []string{RoleAdmin}})
+			c.Next()
+			return
+		}
+
+		raw := bearerToken(c)
+		if raw == "" {
+			c.Next()
+			return
+		}
+		p, err := a.Verify(raw)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatusJSON(401, gin.H{"error": "invalid token"})
+			return
+		}
+		c.Set(principalKey, p)
+		c.Next()
+	}
+}
+
+// Verify parses and validates a token and returns its principal.
+func (a *Authenticator) Verify(raw string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(raw, claims, a.keys.Keyfunc); err != nil {
+		return nil, err
+	}
+	sub, _ := claims.GetSubject()
+	p := &Principal{Subject: sub, Name: sub, Roles: rolesFrom(claims, a.opts.RolesClaim)}
+	for _, k := range []string{"preferred_username", "name", "email"} {
+		if v, ok := claims[k].(string); ok && v != "" {
+			p.Name = v
+			break
+		}
+	}
+	return p, nil
+}
+
+// Require aborts with 401 when the request is not authenticated and with 403
+// when the principal lacks role.
+func Require(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p := FromContext(c)
+		if p == nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(401, gin.H{"error": "authentication required"})
+			return
+		}
+		if !p.Has(role) {
+			c.AbortWithStatusJSON(403, gin.H{"error": "requires role " + role})
+			return
+		}
+		c.Next()
+	}
+}
+
+func FromContext(c *gin.Context) *Principal {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return nil
+	}
+	p, _ := v.(*Principal)
+	return p
+}
+
+func bearerToken(c *gin.Context) string {
+	h := c.GetHeader("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	if c.Request.Method == "GET" {
+		return c.Query("access_token")
+	}
+	return ""
+}
+
+// rolesFrom reads a list of roles (array or space separated string) from a
+// possibly nested claim.
+func rolesFrom(claims jwt.MapClaims, path string) []string {
+	var v any = map[string]any(claims)
+	for _, part := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+
+	var roles []string
+	switch vv := v.(type) {
+	case []any:
+		for _, r := range vv {
+			if s, ok := r.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+	case string:
+		roles = strings.Fields(vv)
+	}
+	return roles
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestPrincipalHas(t *testing.T) {
+	ranger := &Principal{Roles: []string{"other", RoleRanger}}
+	if !ranger.Has(RoleViewer) || !ranger.Has(RoleRanger) || ranger.Has(RoleAdmin) {
+		t.Fatal("ranger role hierarchy broken")
+	}
+	if (&Principal{Roles: []string{"other"}}).Has(RoleViewer) {
+		t.Fatal("unknown role must not grant access")
+	}
+	if (*Principal)(nil).Has(RoleViewer) {
+		t.Fatal("nil principal must not grant access")
+	}
+}
+
+func TestRolesFrom(t *testing.T) {
+	claims := jwt.MapClaims{
+		"roles":        []any{"viewer", "ranger"},
+		"scope":        "viewer admin",
+		"realm_access": map[string]any{"roles": []any{"admin"}},
+	}
+	for path, want := range map[string]int{"roles": 2, "scope": 2, "realm_access.roles": 1, "missing.roles": 0} {
+		if got := rolesFrom(claims, path); len(got) != want {
+			t.Errorf("%s: %v", path, got)
+		}
+	}
+}
+
+func TestVerifyRSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "k1", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	}})
+	keys, err := ParseJWKS(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("keys = %d, encryption key must be skipped", len(keys))
+	}
+	a := New(&KeySet{keys: keys, lastRefresh: time.Now()}, Options{Issuer: "https://idp"})
+
+	sign := func(claims jwt.MapClaims) string {
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		tok.Header["kid"] = "k1"
+		s, err := tok.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	p, err := a.Verify(sign(jwt.MapClaims{
+		"sub": "abc", "iss": "https://idp", "preferred_username": "jan",
+		"roles": []any{"ranger"}, "exp": time.Now().Add(time.Minute).Unix(),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Subject != "abc" || p.Name != "jan" || !p.Has(RoleRanger) {
+		t.Fatalf("principal = %+v", p)
+	}
+
+	if _, err := a.Verify(sign(jwt.MapClaims{"sub": "abc", "iss": "https://idp", "exp": time.Now().Add(-time.Hour).Unix()})); err == nil {
+		t.Fatal("expired token accepted")
+	}
+	if _, err := a.Verify(sign(jwt.MapClaims{"sub": "abc", "iss": "https://other", "exp": time.Now().Add(time.Minute).Unix()})); err == nil {
+		t.Fatal("token from another issuer accepted")
+	}
+	if _, err := a.Verify(sign(jwt.MapClaims{"sub": "abc", "iss": "https://idp"})); err == nil {
+		t.Fatal("token without exp accepted")
+	}
+}
@@ -0,0 +1,115 @@
+// Package client is a typed Go client for the EC2 worker API. The methods in
+// zz_generated.go are generated from api.Operations, the same table that
+// backs /openapi.json.
+package client
+
+//go:generate go run ../cmd/apigen -o zz_generated.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/api"
+)
+
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	// JWT bearer token; the operator name is taken from it
+	Token string
+	// sent as X-Operator, used only when the API runs without authentication
+	Operator string
+}
+
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+// Error is a non-2xx response of the API.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("api: %d %s", e.StatusCode, e.Message)
+}
+
+// ListStoredSources reads sources from the source table (GET /sources with
+// params.State set), which returns alert keys instead of full alerts.
+func (c *Client) ListStoredSources(ctx context.Context, params ListSourcesParams) (*api.StoredSourceList, error) {
+	if params.State == "" {
+		params.State = "all"
+	}
+	var out api.StoredSourceList
+	if err := c.do(ctx, "GET", "/sources", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, q url.Values, body, out any) error {
+	resp, err := c.raw(ctx, method, path, q, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// raw sends the request and returns the response when the status is 2xx.
+func (c *Client) raw(ctx context.Context, method, path string, q url.Values, body any) (*http.Response, error) {
+	u := c.BaseURL + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.Operator != "" {
+		req.Header.Set("X-Operator", c.Operator)
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		var e api.Error
+		if b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16)); json.Unmarshal(b, &e) == nil && e.Error != "" {
+			apiErr.Message = e.Error
+		} else {
+			apiErr.Message = strings.TrimSpace(string(b))
+		}
+		return nil, apiErr
+	}
+	return resp, nil
+}
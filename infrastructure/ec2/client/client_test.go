@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/api"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+func TestGeneratedUpToDate(t *testing.T) {
+	want, err := api.GenerateClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("zz_generated.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Fatal("zz_generated.go is stale, run: go generate ./client")
+	}
+}
+
+func TestListAlerts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/alerts" {
+			w.WriteHeader(404)
+			json.NewEncoder(w).Encode(api.Error{Error: "not found"})
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("deviceId") != "dev-1" || q.Get("limit") != "10" || q.Get("from") != "2025-12-03T20:00:00Z" || q.Has("cursor") {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		json.NewEncoder(w).Encode(api.AlertList{Count: 1, Alerts: []models.Alert{{DeviceID: "dev-1"}}, NextCursor: "abc"})
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+	page, err := c.ListAlerts(context.Background(), ListAlertsParams{
+		DeviceID: "dev-1",
+		Limit:    10,
+		From:     time.Date(2025, 12, 3, 20, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Count != 1 || page.Alerts[0].DeviceID != "dev-1" || page.NextCursor != "abc" {
+		t.Fatalf("page = %+v", page)
+	}
+
+	_, err = c.GetAlert(context.Background(), "dev-1", "2025-12-03T20:00:00Z")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 404 || apiErr.Message != "not found" {
+		t.Fatalf("err = %v", err)
+	}
+}
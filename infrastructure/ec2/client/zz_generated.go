@@ -0,0 +1,493 @@
+// Code generated by cmd/apigen from api.Operations; DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/api"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/export"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/queue"
+)
+
+// Health: Circuit breaker state of AWS dependencies with liveness and readiness details (GET /health).
+func (c *Client) Health(ctx context.Context) (*api.Health, error) {
+	var out api.Health
+	if err := c.do(ctx, "GET", "/health", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Liveness: Liveness probe (status only); 503 when the consumer loop is stalled (GET /healthz).
+func (c *Client) Liveness(ctx context.Context) (*api.Probe, error) {
+	var out api.Probe
+	if err := c.do(ctx, "GET", "/healthz", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Readiness: Readiness probe (status only, checks cached briefly); 503 when not ready (GET /readyz).
+func (c *Client) Readiness(ctx context.Context) (*api.Probe, error) {
+	var out api.Probe
+	if err := c.do(ctx, "GET", "/readyz", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Metrics: Prometheus metrics of the queue consumer, alert pipeline and HTTP API (GET /metrics).
+// The caller must close the response body.
+func (c *Client) Metrics(ctx context.Context) (*http.Response, error) {
+	return c.raw(ctx, "GET", "/metrics", nil, nil)
+}
+
+// StreamParams are the query parameters of Stream. Zero values are not sent.
+type StreamParams struct {
+	Types       string // comma separated event types
+	LastEventID string // resume after this event
+}
+
+func (p StreamParams) values() url.Values {
+	q := url.Values{}
+	if p.Types != "" {
+		q.Set("types", p.Types)
+	}
+	if p.LastEventID != "" {
+		q.Set("lastEventId", p.LastEventID)
+	}
+	return q
+}
+
+// Stream: Server-Sent Events with new alerts and source changes (GET /stream).
+// The caller must close the response body.
+func (c *Client) Stream(ctx context.Context, params StreamParams) (*http.Response, error) {
+	return c.raw(ctx, "GET", "/stream", params.values(), nil)
+}
+
+// ListSensorsParams are the query parameters of ListSensors. Zero values are not sent.
+type ListSensorsParams struct {
+	Status string // online, stale or offline
+}
+
+func (p ListSensorsParams) values() url.Values {
+	q := url.Values{}
+	if p.Status != "" {
+		q.Set("status", p.Status)
+	}
+	return q
+}
+
+// ListSensors: Registered sensors with computed online/stale/offline status (GET /sensors).
+func (c *Client) ListSensors(ctx context.Context, params ListSensorsParams) ([]models.Sensor, error) {
+	var out []models.Sensor
+	if err := c.do(ctx, "GET", "/sensors", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListSensorsGeoJSONParams are the query parameters of ListSensorsGeoJSON. Zero values are not sent.
+type ListSensorsGeoJSONParams struct {
+	Status string // online, stale or offline
+}
+
+func (p ListSensorsGeoJSONParams) values() url.Values {
+	q := url.Values{}
+	if p.Status != "" {
+		q.Set("status", p.Status)
+	}
+	return q
+}
+
+// ListSensorsGeoJSON: Registered sensors as GeoJSON (GET /sensors.geojson).
+func (c *Client) ListSensorsGeoJSON(ctx context.Context, params ListSensorsGeoJSONParams) (*export.FeatureCollection, error) {
+	var out export.FeatureCollection
+	if err := c.do(ctx, "GET", "/sensors.geojson", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SensorTelemetryParams are the query parameters of SensorTelemetry. Zero values are not sent.
+type SensorTelemetryParams struct {
+	From string // RFC3339, default to-24h
+	To   string // RFC3339, default now
+	Step string // bucket duration (e.g. 15m) or raw; automatic when empty
+}
+
+func (p SensorTelemetryParams) values() url.Values {
+	q := url.Values{}
+	if p.From != "" {
+		q.Set("from", p.From)
+	}
+	if p.To != "" {
+		q.Set("to", p.To)
+	}
+	if p.Step != "" {
+		q.Set("step", p.Step)
+	}
+	return q
+}
+
+// SensorTelemetry: Telemetry of one sensor, downsampled (GET /sensors/:id/telemetry).
+func (c *Client) SensorTelemetry(ctx context.Context, id string, params SensorTelemetryParams) (*api.TelemetrySeries, error) {
+	var out api.TelemetrySeries
+	if err := c.do(ctx, "GET", "/sensors/"+url.PathEscape(id)+"/telemetry", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSourcesParams are the query parameters of ListSources. Zero values are not sent.
+type ListSourcesParams struct {
+	From  time.Time // start of the time window
+	To    time.Time // end of the time window
+	State string    // active, expired or all; reads the source table
+}
+
+func (p ListSourcesParams) values() url.Values {
+	q := url.Values{}
+	if !p.From.IsZero() {
+		q.Set("from", p.From.UTC().Format(time.RFC3339))
+	}
+	if !p.To.IsZero() {
+		q.Set("to", p.To.UTC().Format(time.RFC3339))
+	}
+	if p.State != "" {
+		q.Set("state", p.State)
+	}
+	return q
+}
+
+// ListSources: Localized sound sources (GET /sources).
+func (c *Client) ListSources(ctx context.Context, params ListSourcesParams) (*api.SourceList, error) {
+	var out api.SourceList
+	if err := c.do(ctx, "GET", "/sources", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSourcesGeoJSONParams are the query parameters of ListSourcesGeoJSON. Zero values are not sent.
+type ListSourcesGeoJSONParams struct {
+	From  time.Time // start of the time window
+	To    time.Time // end of the time window
+	State string    // active, expired or all; reads the source table
+}
+
+func (p ListSourcesGeoJSONParams) values() url.Values {
+	q := url.Values{}
+	if !p.From.IsZero() {
+		q.Set("from", p.From.UTC().Format(time.RFC3339))
+	}
+	if !p.To.IsZero() {
+		q.Set("to", p.To.UTC().Format(time.RFC3339))
+	}
+	if p.State != "" {
+		q.Set("state", p.State)
+	}
+	return q
+}
+
+// ListSourcesGeoJSON: Localized sound sources as GeoJSON (GET /sources.geojson).
+func (c *Client) ListSourcesGeoJSON(ctx context.Context, params ListSourcesGeoJSONParams) (*export.FeatureCollection, error) {
+	var out export.FeatureCollection
+	if err := c.do(ctx, "GET", "/sources.geojson", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ExportSourcesKMLParams are the query parameters of ExportSourcesKML. Zero values are not sent.
+type ExportSourcesKMLParams struct {
+	From  time.Time // start of the time window
+	To    time.Time // end of the time window
+	State string    // active, expired or all; reads the source table
+	Zone  string    // configured zone name
+	Bbox  string    // minLon,minLat,maxLon,maxLat
+}
+
+func (p ExportSourcesKMLParams) values() url.Values {
+	q := url.Values{}
+	if !p.From.IsZero() {
+		q.Set("from", p.From.UTC().Format(time.RFC3339))
+	}
+	if !p.To.IsZero() {
+		q.Set("to", p.To.UTC().Format(time.RFC3339))
+	}
+	if p.State != "" {
+		q.Set("state", p.State)
+	}
+	if p.Zone != "" {
+		q.Set("zone", p.Zone)
+	}
+	if p.Bbox != "" {
+		q.Set("bbox", p.Bbox)
+	}
+	return q
+}
+
+// ExportSourcesKML: Sources as KML for Google Earth (GET /sources.kml).
+// The caller must close the response body.
+func (c *Client) ExportSourcesKML(ctx context.Context, params ExportSourcesKMLParams) (*http.Response, error) {
+	return c.raw(ctx, "GET", "/sources.kml", params.values(), nil)
+}
+
+// ExportSourcesGPXParams are the query parameters of ExportSourcesGPX. Zero values are not sent.
+type ExportSourcesGPXParams struct {
+	From  time.Time // start of the time window
+	To    time.Time // end of the time window
+	State string    // active, expired or all; reads the source table
+	Zone  string    // configured zone name
+	Bbox  string    // minLon,minLat,maxLon,maxLat
+}
+
+func (p ExportSourcesGPXParams) values() url.Values {
+	q := url.Values{}
+	if !p.From.IsZero() {
+		q.Set("from", p.From.UTC().Format(time.RFC3339))
+	}
+	if !p.To.IsZero() {
+		q.Set("to", p.To.UTC().Format(time.RFC3339))
+	}
+	if p.State != "" {
+		q.Set("state", p.State)
+	}
+	if p.Zone != "" {
+		q.Set("zone", p.Zone)
+	}
+	if p.Bbox != "" {
+		q.Set("bbox", p.Bbox)
+	}
+	return q
+}
+
+// ExportSourcesGPX: Sources as GPX waypoints (GET /sources.gpx).
+// The caller must close the response body.
+func (c *Client) ExportSourcesGPX(ctx context.Context, params ExportSourcesGPXParams) (*http.Response, error) {
+	return c.raw(ctx, "GET", "/sources.gpx", params.values(), nil)
+}
+
+// GetSource: One source, live or stored (GET /sources/:id).
+func (c *Client) GetSource(ctx context.Context, id string) (*processor.SourceGroup, error) {
+	var out processor.SourceGroup
+	if err := c.do(ctx, "GET", "/sources/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSourceMapParams are the query parameters of GetSourceMap. Zero values are not sent.
+type GetSourceMapParams struct {
+	Size int // longest side in pixels
+}
+
+func (p GetSourceMapParams) values() url.Values {
+	q := url.Values{}
+	if p.Size != 0 {
+		q.Set("size", strconv.Itoa(p.Size))
+	}
+	return q
+}
+
+// GetSourceMap: PNG map of a source and its alerts (GET /sources/:id/map.png).
+// The caller must close the response body.
+func (c *Client) GetSourceMap(ctx context.Context, id string, params GetSourceMapParams) (*http.Response, error) {
+	return c.raw(ctx, "GET", "/sources/"+url.PathEscape(id)+"/map.png", params.values(), nil)
+}
+
+// ListAlertsParams are the query parameters of ListAlerts. Zero values are not sent.
+type ListAlertsParams struct {
+	From     time.Time // start of the time window
+	To       time.Time // end of the time window
+	DeviceID string    // single sensor
+	Status   string    // comma separated statuses
+	Class    string    // comma separated sound classes
+	Bbox     string    // minLon,minLat,maxLon,maxLat
+	Limit    int       // page size, max 500
+	Cursor   string    // nextCursor of the previous page
+}
+
+func (p ListAlertsParams) values() url.Values {
+	q := url.Values{}
+	if !p.From.IsZero() {
+		q.Set("from", p.From.UTC().Format(time.RFC3339))
+	}
+	if !p.To.IsZero() {
+		q.Set("to", p.To.UTC().Format(time.RFC3339))
+	}
+	if p.DeviceID != "" {
+		q.Set("deviceId", p.DeviceID)
+	}
+	if p.Status != "" {
+		q.Set("status", p.Status)
+	}
+	if p.Class != "" {
+		q.Set("class", p.Class)
+	}
+	if p.Bbox != "" {
+		q.Set("bbox", p.Bbox)
+	}
+	if p.Limit != 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Cursor != "" {
+		q.Set("cursor", p.Cursor)
+	}
+	return q
+}
+
+// ListAlerts: Alerts in a time window, cursor paginated (GET /alerts).
+func (c *Client) ListAlerts(ctx context.Context, params ListAlertsParams) (*api.AlertList, error) {
+	var out api.AlertList
+	if err := c.do(ctx, "GET", "/alerts", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAlertsGeoJSONParams are the query parameters of ListAlertsGeoJSON. Zero values are not sent.
+type ListAlertsGeoJSONParams struct {
+	From     time.Time // start of the time window
+	To       time.Time // end of the time window
+	DeviceID string    // single sensor
+	Status   string    // comma separated statuses
+	Class    string    // comma separated sound classes
+	Bbox     string    // minLon,minLat,maxLon,maxLat
+	Limit    int       // page size, max 500
+	Cursor   string    // nextCursor of the previous page
+}
+
+func (p ListAlertsGeoJSONParams) values() url.Values {
+	q := url.Values{}
+	if !p.From.IsZero() {
+		q.Set("from", p.From.UTC().Format(time.RFC3339))
+	}
+	if !p.To.IsZero() {
+		q.Set("to", p.To.UTC().Format(time.RFC3339))
+	}
+	if p.DeviceID != "" {
+		q.Set("deviceId", p.DeviceID)
+	}
+	if p.Status != "" {
+		q.Set("status", p.Status)
+	}
+	if p.Class != "" {
+		q.Set("class", p.Class)
+	}
+	if p.Bbox != "" {
+		q.Set("bbox", p.Bbox)
+	}
+	if p.Limit != 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Cursor != "" {
+		q.Set("cursor", p.Cursor)
+	}
+	return q
+}
+
+// ListAlertsGeoJSON: Alerts as GeoJSON (GET /alerts.geojson).
+func (c *Client) ListAlertsGeoJSON(ctx context.Context, params ListAlertsGeoJSONParams) (*export.FeatureCollection, error) {
+	var out export.FeatureCollection
+	if err := c.do(ctx, "GET", "/alerts.geojson", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAlert: Alert detail with media links (GET /alerts/:deviceId/:ts).
+func (c *Client) GetAlert(ctx context.Context, deviceID string, ts string) (*api.AlertDetail, error) {
+	var out api.AlertDetail
+	if err := c.do(ctx, "GET", "/alerts/"+url.PathEscape(deviceID)+"/"+url.PathEscape(ts), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateAlertStatus: Change the workflow status of an alert (PATCH /alerts/:deviceId/:ts).
+func (c *Client) UpdateAlertStatus(ctx context.Context, deviceID string, ts string, body api.StatusUpdate) (*models.Alert, error) {
+	var out models.Alert
+	if err := c.do(ctx, "PATCH", "/alerts/"+url.PathEscape(deviceID)+"/"+url.PathEscape(ts), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAlertAudio: Audio clip, supports Range requests (GET /alerts/:deviceId/:ts/audio).
+// The caller must close the response body.
+func (c *Client) GetAlertAudio(ctx context.Context, deviceID string, ts string) (*http.Response, error) {
+	return c.raw(ctx, "GET", "/alerts/"+url.PathEscape(deviceID)+"/"+url.PathEscape(ts)+"/audio", nil, nil)
+}
+
+// GetAlertMedia: Media derived from the audio clip, e.g. spectrogram.png (GET /alerts/:deviceId/:ts/media/:kind).
+// The caller must close the response body.
+func (c *Client) GetAlertMedia(ctx context.Context, deviceID string, ts string, kind string) (*http.Response, error) {
+	return c.raw(ctx, "GET", "/alerts/"+url.PathEscape(deviceID)+"/"+url.PathEscape(ts)+"/media/"+url.PathEscape(kind), nil, nil)
+}
+
+// ListQueueMessagesParams are the query parameters of ListQueueMessages. Zero values are not sent.
+type ListQueueMessagesParams struct {
+	Max int // 1-100
+}
+
+func (p ListQueueMessagesParams) values() url.Values {
+	q := url.Values{}
+	if p.Max != 0 {
+		q.Set("max", strconv.Itoa(p.Max))
+	}
+	return q
+}
+
+// ListQueueMessages: Peek at DLQ or quarantine messages (GET /admin/queues/:queue/messages).
+func (c *Client) ListQueueMessages(ctx context.Context, queueName string, params ListQueueMessagesParams) (*api.DeadLetterList, error) {
+	var out api.DeadLetterList
+	if err := c.do(ctx, "GET", "/admin/queues/"+url.PathEscape(queueName)+"/messages", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PurgeQueue: Purge the whole queue (DELETE /admin/queues/:queue/messages).
+func (c *Client) PurgeQueue(ctx context.Context, queueName string) (*api.Purged, error) {
+	var out api.Purged
+	if err := c.do(ctx, "DELETE", "/admin/queues/"+url.PathEscape(queueName)+"/messages", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetQueueMessage: One queued message (GET /admin/queues/:queue/messages/:id).
+func (c *Client) GetQueueMessage(ctx context.Context, queueName string, id string) (*queue.DeadLetter, error) {
+	var out queue.DeadLetter
+	if err := c.do(ctx, "GET", "/admin/queues/"+url.PathEscape(queueName)+"/messages/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RedriveQueueMessage: Send a message back to the alerts queue (POST /admin/queues/:queue/messages/:id/redrive).
+func (c *Client) RedriveQueueMessage(ctx context.Context, queueName string, id string) (*api.Redriven, error) {
+	var out api.Redriven
+	if err := c.do(ctx, "POST", "/admin/queues/"+url.PathEscape(queueName)+"/messages/"+url.PathEscape(id)+"/redrive", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PurgeQueueMessage: Delete one message (DELETE /admin/queues/:queue/messages/:id).
+func (c *Client) PurgeQueueMessage(ctx context.Context, queueName string, id string) (*api.Purged, error) {
+	var out api.Purged
+	if err := c.do(ctx, "DELETE", "/admin/queues/"+url.PathEscape(queueName)+"/messages/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,25 @@
+// Command apigen regenerates client/zz_generated.go from api.Operations.
+//
+//	go generate ./client
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/api"
+)
+
+func main() {
+	out := flag.String("o", "zz_generated.go", "output file")
+	flag.Parse()
+
+	src, err := api.GenerateClient()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,62 @@
+// backfill sets tsDay on alerts written before the byDay index existed, so
+// they show up in time-range queries. It is safe to run more than once.
+//
+//	backfill [-segments 4] [-dry-run]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/config"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+)
+
+func main() {
+	segments := flag.Int("segments", 4, "parallel scan segments")
+	dryRun := flag.Bool("dry-run", false, "only count alerts that would be updated")
+	flag.Parse()
+
+	if err := config.Load(); err != nil {
+		fatal(err)
+	}
+	cfg := config.AppConfig.AWS
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	awsCfg, err := awsConfig.LoadDefaultConfig(ctx, awsConfig.WithRegion(cfg.Region))
+	if err != nil {
+		fatal(err)
+	}
+	repo := repository.NewRepo(dynamodb.NewFromConfig(awsCfg), nil, cfg.AlertsTable, cfg.DevicesTable)
+
+	start := time.Now()
+	var last time.Time
+	stats, err := repo.BackfillDayBuckets(ctx, *segments, *dryRun, func(s repository.BackfillStats) {
+		if time.Since(last) < time.Second {
+			return
+		}
+		last = time.Now()
+		fmt.Fprintf(os.Stderr, "scanned %d, updated %d, skipped %d\n", s.Scanned, s.Updated, s.Skipped)
+	})
+	verb := "updated"
+	if *dryRun {
+		verb = "to update"
+	}
+	fmt.Printf("scanned %d, %s %d, skipped %d in %s\n", stats.Scanned, verb, stats.Updated, stats.Skipped, time.Since(start).Round(time.Millisecond))
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "backfill: %v\n", err)
+	os.Exit(1)
+}
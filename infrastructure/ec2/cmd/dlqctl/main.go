@@ -0,0 +1,113 @@
+// dlqctl inspects and repairs the alerts dead-letter and quarantine queues.
+//
+//	dlqctl [-queue dlq|quarantine] list [max]
+//	dlqctl [-queue dlq|quarantine] get <messageId>
+//	dlqctl [-queue dlq|quarantine] redrive <messageId>
+//	dlqctl [-queue dlq|quarantine] purge [messageId]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/config"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/queue"
+)
+
+func main() {
+	name := flag.String("queue", "dlq", "queue to operate on: dlq or quarantine")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: dlqctl [-queue dlq|quarantine] list [max] | get <id> | redrive <id> | purge [id]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := config.Load(); err != nil {
+		fatal(err)
+	}
+	cfg := config.AppConfig.AWS
+
+	url := cfg.DLQURL
+	if *name == "quarantine" {
+		url = cfg.QuarantineURL
+	} else if *name != "dlq" {
+		fatal(fmt.Errorf("unknown queue %q", *name))
+	}
+	if url == "" {
+		fatal(fmt.Errorf("queue %q is not configured", *name))
+	}
+
+	ctx := context.Background()
+	awsCfg, err := awsConfig.LoadDefaultConfig(ctx, awsConfig.WithRegion(cfg.Region))
+	if err != nil {
+		fatal(err)
+	}
+	dlq := queue.NewDLQ(sqs.NewFromConfig(awsCfg), url, cfg.SQSURL)
+
+	cmd, arg := flag.Arg(0), flag.Arg(1)
+	switch cmd {
+	case "list":
+		max := 10
+		if arg != "" {
+			if max, err = strconv.Atoi(arg); err != nil {
+				fatal(err)
+			}
+		}
+		msgs, err := dlq.List(ctx, max)
+		if err != nil {
+			fatal(err)
+		}
+		for _, m := range msgs {
+			fmt.Printf("%s\treceives=%d\treason=%s\tsent=%s\t%s\n", m.MessageID, m.ReceiveCount, m.Reason, m.SentAt.Format("2006-01-02T15:04:05Z"), m.Body)
+		}
+	case "get":
+		requireID(arg)
+		m, err := dlq.Get(ctx, arg)
+		if err != nil {
+			fatal(err)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(m)
+	case "redrive":
+		requireID(arg)
+		if err := dlq.Redrive(ctx, arg); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("redriven %s\n", arg)
+	case "purge":
+		if err := dlq.Purge(ctx, arg); err != nil {
+			fatal(err)
+		}
+		if arg == "" {
+			fmt.Printf("purged queue %s\n", *name)
+		} else {
+			fmt.Printf("purged %s\n", arg)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func requireID(id string) {
+	if id == "" {
+		fatal(fmt.Errorf("message id required"))
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "dlqctl: %v\n", err)
+	os.Exit(1)
+}
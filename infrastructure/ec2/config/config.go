@@ -4,20 +4,104 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	AWS AWSConfig `yaml:"aws"`
+	AWS        AWSConfig        `yaml:"aws"`
+	Server     ServerConfig     `yaml:"server"`
+	Resilience ResilienceConfig `yaml:"resilience"`
+	Zones      []ZoneConfig     `yaml:"zones"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Sensors    SensorsConfig    `yaml:"sensors"`
+	Edge       EdgeConfig       `yaml:"edge"`
+	Retention  RetentionConfig  `yaml:"retention"`
+}
+
+// RetentionConfig sets how many days after its ts an alert is kept before
+// DynamoDB TTL removes it. Alerts of confirmed incidents never expire.
+type RetentionConfig struct {
+	// leave the TTL from ingest untouched, except for confirmed incidents
+	Disabled          bool `yaml:"disabled"`
+	FalsePositiveDays int  `yaml:"false_positive_days"`
+	// must match ALERT_TTL_DAYS of lambda-alert, which sets it at ingest
+	UnattachedDays int `yaml:"unattached_days"`
+	AttachedDays   int `yaml:"attached_days"`
+}
+
+// EdgeConfig runs the worker on a gateway inside the forest: alerts, sensors
+// and sources in a local bbolt file, an in-process queue instead of SQS, and
+// upload to the aws.* tables and bucket whenever the uplink works.
+type EdgeConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// written as origin on synced alerts; must be unique per gateway
+	GatewayID string `yaml:"gateway_id"`
+	// edge.db and the audio/ directory
+	DataDir string `yaml:"data_dir"`
+	// shared X-Ingest-Token of the sensors, empty = no check
+	IngestToken  string        `yaml:"ingest_token"`
+	SyncInterval time.Duration `yaml:"sync_interval"`
+	SyncBatch    int           `yaml:"sync_batch"`
+}
+
+// SensorsConfig sets when a sensor counts as stale / offline, measured from the
+// newer of its last alert and last heartbeat.
+type SensorsConfig struct {
+	StaleAfter   time.Duration `yaml:"stale_after"`
+	OfflineAfter time.Duration `yaml:"offline_after"`
+	// how often the worker checks for sensors going offline
+	CheckInterval time.Duration `yaml:"check_interval"`
+	// how often the cached sensor registry is reloaded from the devices table
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+type AuthConfig struct {
+	// run without authentication (every request is an admin), local dev only
+	Disabled bool `yaml:"disabled"`
+	// JWKS file path or URL (e.g. https://cognito-idp.../.well-known/jwks.json)
+	JWKS        string        `yaml:"jwks"`
+	JWKSRefresh time.Duration `yaml:"jwks_refresh"`
+	Issuer      string        `yaml:"issuer"`
+	Audience    string        `yaml:"audience"`
+	RolesClaim  string        `yaml:"roles_claim"`
+}
+
+// ZoneConfig names an area (forest district, patrol sector) that exports can be
+// filtered by. BBox is minLon,minLat,maxLon,maxLat, the same order as ?bbox=.
+type ZoneConfig struct {
+	Name string    `yaml:"name"`
+	BBox []float64 `yaml:"bbox"`
+}
+
+type ServerConfig struct {
+	Addr            string        `yaml:"addr"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	DrainTimeout    time.Duration `yaml:"drain_timeout"`
+	// allowed CORS origins; empty allows all
+	CORSOrigins []string `yaml:"cors_origins"`
+	// prefix of the API as seen by clients (e.g. "/api" behind nginx), used
+	// for links such as audioUrl; empty means the worker root
+	PublicBaseURL string `yaml:"public_base_url"`
 }
 
 type AWSConfig struct {
-	Region       string `yaml:"region"`
-	SQSURL       string `yaml:"sqs_url"`
-	DevicesTable string `yaml:"devices_table"`
-	AlertsTable  string `yaml:"alerts_table"`
-	BucketName   string `yaml:"bucket_name"`
+	Region        string `yaml:"region"`
+	SQSURL        string `yaml:"sqs_url"`
+	DLQURL        string `yaml:"dlq_url"`
+	QuarantineURL string `yaml:"quarantine_url"`
+	DevicesTable  string `yaml:"devices_table"`
+	AlertsTable   string `yaml:"alerts_table"`
+	SourcesTable  string `yaml:"sources_table"`
+	// optional; enables GET /sensors/:id/telemetry
+	TelemetryTable string `yaml:"telemetry_table"`
+	BucketName     string `yaml:"bucket_name"`
+}
+
+type ResilienceConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold"`
+	OpenTimeout      time.Duration `yaml:"open_timeout"`
 }
 
 //go:embed configuration.yml
@@ -33,8 +117,84 @@ func Load() error {
 		return fmt.Errorf("cannot parse yaml: %w", err)
 	}
 
-	if AppConfig.AWS.SQSURL == "" || AppConfig.AWS.Region == "" {
+	if AppConfig.AWS.Region == "" || (AppConfig.AWS.SQSURL == "" && !AppConfig.Edge.Enabled) {
 		return fmt.Errorf("invalid config: region=%q sqs_url=%q", AppConfig.AWS.Region, AppConfig.AWS.SQSURL)
 	}
+	if e := &AppConfig.Edge; e.Enabled {
+		if e.GatewayID == "" {
+			return errors.New("invalid config: edge.gateway_id is required in edge mode")
+		}
+		if e.DataDir == "" {
+			e.DataDir = "data"
+		}
+		if e.SyncInterval == 0 {
+			e.SyncInterval = time.Minute
+		}
+		if e.SyncBatch == 0 {
+			e.SyncBatch = 100
+		}
+	}
+
+	for _, z := range AppConfig.Zones {
+		if z.Name == "" || len(z.BBox) != 4 || z.BBox[0] > z.BBox[2] || z.BBox[1] > z.BBox[3] {
+			return fmt.Errorf("invalid zone %q: bbox must be minLon,minLat,maxLon,maxLat", z.Name)
+		}
+	}
+
+	if !AppConfig.Auth.Disabled && AppConfig.Auth.JWKS == "" {
+		return errors.New("invalid config: auth.jwks is required (or set auth.disabled: true)")
+	}
+	if AppConfig.Auth.JWKSRefresh == 0 {
+		AppConfig.Auth.JWKSRefresh = time.Hour
+	}
+	if AppConfig.Auth.RolesClaim == "" {
+		AppConfig.Auth.RolesClaim = "roles"
+	}
+
+	if AppConfig.Server.Addr == "" {
+		AppConfig.Server.Addr = ":8080"
+	}
+	if AppConfig.Server.ShutdownTimeout == 0 {
+		AppConfig.Server.ShutdownTimeout = 10 * time.Second
+	}
+	if AppConfig.Server.DrainTimeout == 0 {
+		AppConfig.Server.DrainTimeout = 30 * time.Second
+	}
+	if AppConfig.Sensors.StaleAfter == 0 {
+		AppConfig.Sensors.StaleAfter = 5 * time.Minute
+	}
+	if AppConfig.Sensors.OfflineAfter == 0 {
+		AppConfig.Sensors.OfflineAfter = 15 * time.Minute
+	}
+	if AppConfig.Sensors.CheckInterval == 0 {
+		AppConfig.Sensors.CheckInterval = time.Minute
+	}
+	if AppConfig.Sensors.RefreshInterval == 0 {
+		AppConfig.Sensors.RefreshInterval = time.Minute
+	}
+	if r := &AppConfig.Retention; !r.Disabled {
+		if r.FalsePositiveDays == 0 {
+			r.FalsePositiveDays = 14
+		}
+		if r.UnattachedDays == 0 {
+			r.UnattachedDays = 30
+		}
+		if r.AttachedDays == 0 {
+			r.AttachedDays = 180
+		}
+		if r.FalsePositiveDays < 0 || r.UnattachedDays < 0 || r.AttachedDays < 0 {
+			return errors.New("invalid config: retention days must be positive")
+		}
+	}
+	if AppConfig.Sensors.StaleAfter >= AppConfig.Sensors.OfflineAfter {
+		return fmt.Errorf("invalid config: sensors.stale_after (%s) must be shorter than offline_after (%s)",
+			AppConfig.Sensors.StaleAfter, AppConfig.Sensors.OfflineAfter)
+	}
+	if AppConfig.Resilience.FailureThreshold == 0 {
+		AppConfig.Resilience.FailureThreshold = 5
+	}
+	if AppConfig.Resilience.OpenTimeout == 0 {
+		AppConfig.Resilience.OpenTimeout = 30 * time.Second
+	}
 	return nil
 }
@@ -0,0 +1,209 @@
+package edge
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+)
+
+// same limit as API Gateway in front of lambda-alert
+const maxIngestBody = 10 << 20
+
+// sensor clocks may drift; a heartbeat ts further off than this is replaced by
+// gateway time (as in lambda-heartbeat)
+const maxClockSkew = 5 * time.Minute
+
+// Ingest accepts the requests sensors send to lambda-alert and lambda-heartbeat
+// in the cloud, with the same bodies, and stores them on the gateway.
+type Ingest struct {
+	store    *repository.BoltStore
+	queue    *Queue
+	audioDir string
+	token    string
+	logger   *log.Logger
+	// TTL for new alerts, as lambda-alert sets it; nil = none
+	retention *models.Retention
+}
+
+// NewIngest creates the ingest handlers. token is the shared X-Ingest-Token of
+// the sensors; empty disables the check (sensors on a closed network).
+func NewIngest(store *repository.BoltStore, queue *Queue, audioDir, token string, logger *log.Logger) *Ingest {
+	return &Ingest{store: store, queue: queue, audioDir: audioDir, token: token, logger: logger}
+}
+
+// SetRetention makes new alerts expire like the ones written by lambda-alert.
+func (in *Ingest) SetRetention(r models.Retention) {
+	in.retention = &r
+}
+
+type alertReq struct {
+	DeviceID string  `json:"deviceId"`
+	TS       string  `json:"ts"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	Distance float64 `json:"distance"`
+	Class    string  `json:"class"`
+	AudioB64 string  `json:"audioB64"`
+}
+
+type heartbeatReq struct {
+	DeviceID  string            `json:"deviceId"`
+	TS        string            `json:"ts"`
+	Telemetry *models.Telemetry `json:"telemetry"`
+}
+
+// Authenticate rejects requests without the shared X-Ingest-Token header.
+func (in *Ingest) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if in.token != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Ingest-Token")), []byte(in.token)) != 1 {
+			c.AbortWithStatusJSON(401, gin.H{"error": "invalid ingest token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Alert handles POST /ingest/alert: the clip goes to the audio directory under
+// the same key as in S3, the alert to the local store and its key to the queue.
+func (in *Ingest) Alert(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxIngestBody)
+	var req alertReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "invalid json"})
+		return
+	}
+	if req.DeviceID == "" || req.AudioB64 == "" {
+		c.JSON(400, gin.H{"error": "deviceId and audioB64 required"})
+		return
+	}
+	// the id becomes part of a file path
+	if strings.ContainsAny(req.DeviceID, `/\`) || strings.Contains(req.DeviceID, "..") {
+		c.JSON(400, gin.H{"error": "invalid deviceId"})
+		return
+	}
+	now := time.Now().UTC()
+	ts := now
+	if strings.TrimSpace(req.TS) != "" {
+		t, err := time.Parse(time.RFC3339, req.TS)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "ts must be RFC3339"})
+			return
+		}
+		ts = t.UTC()
+	}
+	req.TS = ts.Format(time.RFC3339)
+
+	audio, err := base64.StdEncoding.DecodeString(req.AudioB64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid base64"})
+		return
+	}
+	sum := sha256.Sum256(audio)
+	sha := hex.EncodeToString(sum[:])
+
+	key := AudioKey(req.DeviceID, req.TS)
+	if err := writeFile(AudioPath(in.audioDir, key), audio); err != nil {
+		in.logger.Printf("ingest: write audio %s: %v", key, err)
+		c.JSON(500, gin.H{"error": "internal server error"})
+		return
+	}
+
+	a := models.Alert{
+		DeviceID:  req.DeviceID,
+		TS:        req.TS,
+		S3Key:     key,
+		Lat:       req.Lat,
+		Lon:       req.Lon,
+		Distance:  req.Distance,
+		Status:    models.StatusNew,
+		Checksum:  sha,
+		CreatedAt: now.Format(time.RFC3339),
+		Class:     strings.ToLower(strings.TrimSpace(req.Class)),
+	}
+	if in.retention != nil {
+		a.TTL = in.retention.TTL(a, false, false)
+	}
+	if err := in.store.RecordAlert(a, now); err != nil {
+		in.logger.Printf("ingest: store alert %s: %v", a.Key(), err)
+		c.JSON(500, gin.H{"error": "internal server error"})
+		return
+	}
+	if err := in.queue.Enqueue(models.Envelope{DeviceID: a.DeviceID, TS: a.TS}); err != nil {
+		in.logger.Printf("ingest: enqueue %s: %v", a.Key(), err)
+		c.JSON(500, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(201, gin.H{"ok": true, "s3Key": key, "ts": a.TS, "sha256": sha})
+}
+
+// Heartbeat handles POST /ingest/heartbeat.
+func (in *Ingest) Heartbeat(c *gin.Context) {
+	var req heartbeatReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "invalid json"})
+		return
+	}
+	if strings.TrimSpace(req.DeviceID) == "" {
+		c.JSON(400, gin.H{"error": "deviceId required"})
+		return
+	}
+
+	now := time.Now().UTC()
+	at := now
+	if t, err := time.Parse(time.RFC3339, req.TS); err == nil && t.Sub(now).Abs() <= maxClockSkew {
+		at = t.UTC()
+	}
+	if req.Telemetry != nil {
+		req.Telemetry.TS = at.Format(time.RFC3339)
+		req.Telemetry.Samples = 0
+	}
+
+	found, err := in.store.RecordHeartbeat(req.DeviceID, at, req.Telemetry)
+	if err != nil {
+		in.logger.Printf("ingest: heartbeat %s: %v", req.DeviceID, err)
+		c.JSON(500, gin.H{"error": "internal server error"})
+		return
+	}
+	if !found {
+		c.JSON(404, gin.H{"error": "unknown device"})
+		return
+	}
+	c.JSON(200, gin.H{"ok": true, "lastHeartbeat": at.Format(time.RFC3339)})
+}
+
+// AudioKey is the object key of a clip, the same layout lambda-alert uses in S3.
+func AudioKey(deviceID, ts string) string {
+	datePath := strings.ReplaceAll(ts[:19], ":", "-")
+	return fmt.Sprintf("%s/%s/%s.wav", deviceID, ts[:10], datePath)
+}
+
+// AudioPath is where the clip with key is kept on the gateway.
+func AudioPath(dir, key string) string {
+	return filepath.Join(dir, filepath.FromSlash(key))
+}
+
+// writeFile writes through a temporary file so a crash never leaves a partial
+// clip that would later be uploaded.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,70 @@
+package edge
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+func TestIngestAlert(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	st := openStore(t)
+	q, err := NewQueue(st.DB(), func(context.Context, models.Envelope) error { return nil }, discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	in := NewIngest(st, q, dir, "secret", discard)
+	r := gin.New()
+	g := r.Group("/ingest", in.Authenticate())
+	g.POST("/alert", in.Alert)
+	g.POST("/heartbeat", in.Heartbeat)
+
+	post := func(path, token, body string) int {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("X-Ingest-Token", token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	clip := base64.StdEncoding.EncodeToString([]byte("RIFF...."))
+	body := `{"deviceId":"AAA-001","ts":"2025-12-03T20:00:00Z","lat":50.06,"lon":19.94,"distance":300,"class":"Chainsaw","audioB64":"` + clip + `"}`
+	if code := post("/ingest/alert", "", body); code != 401 {
+		t.Fatalf("without token: %d", code)
+	}
+	if code := post("/ingest/alert", "secret", body); code != 201 {
+		t.Fatalf("alert: %d", code)
+	}
+
+	a, _ := st.GeAlertByPK(context.Background(), "AAA-001", "2025-12-03T20:00:00Z", true)
+	if a == nil || a.Status != models.StatusNew || a.Class != "chainsaw" || a.S3Key != "AAA-001/2025-12-03/2025-12-03T20-00-00.wav" {
+		t.Fatalf("stored alert = %+v", a)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "AAA-001", "2025-12-03", "2025-12-03T20-00-00.wav")); err != nil || string(data) != "RIFF...." {
+		t.Fatalf("clip = %q, %v", data, err)
+	}
+	if b, _ := q.Backlog(context.Background()); b.Visible != 1 {
+		t.Fatalf("queued = %+v", b)
+	}
+
+	if code := post("/ingest/alert", "secret", `{"deviceId":"../x","audioB64":"`+clip+`"}`); code != 400 {
+		t.Fatalf("path in deviceId: %d", code)
+	}
+	if code := post("/ingest/heartbeat", "secret", `{"deviceId":"AAA-001"}`); code != 200 {
+		t.Fatalf("heartbeat: %d", code)
+	}
+	if code := post("/ingest/heartbeat", "secret", `{"deviceId":"ZZZ-999"}`); code != 404 {
+		t.Fatalf("unknown sensor heartbeat: %d", code)
+	}
+}
@@ -0,0 +1,220 @@
+package edge
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/metrics"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/queue"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/resilience"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketQueue = []byte("queue")
+	bucketDead  = []byte("dead")
+)
+
+// how long Run waits for new messages before looping (keeps /healthz fresh)
+const idleWait = 20 * time.Second
+
+// maxAttempts matches maxReceiveCount of the cloud queue's redrive policy.
+const maxAttempts = 5
+
+// Queue replaces SQS on the gateway: envelopes are stored in a bucket of the
+// edge database, so messages accepted before a restart or power loss are
+// still handled afterwards. Messages are handled one at a time in arrival
+// order; a failed message is retried with backoff and blocks the ones behind
+// it, like the FIFO queue in the cloud. After maxAttempts failures it is moved
+// to the dead bucket, as the cloud queue moves it to the DLQ.
+type Queue struct {
+	db          *bolt.DB
+	handle      queue.HandlerFunc
+	logger      *log.Logger
+	backoff     *resilience.Backoff
+	notify      chan struct{}
+	maxAttempts int
+
+	running       atomic.Bool
+	lastLoop      atomic.Int64
+	lastProcessed atomic.Int64
+	processed     atomic.Uint64
+	failed        atomic.Uint64
+}
+
+func NewQueue(db *bolt.DB, handler queue.HandlerFunc, logger *log.Logger) (*Queue, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketQueue, bucketDead} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Queue{
+		db:          db,
+		handle:      handler,
+		logger:      logger,
+		backoff:     resilience.NewBackoff(500*time.Millisecond, time.Minute),
+		notify:      make(chan struct{}, 1),
+		maxAttempts: maxAttempts,
+	}, nil
+}
+
+// Enqueue stores env for Run.
+func (q *Queue) Enqueue(env models.Envelope) error {
+	raw, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	err = q.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketQueue)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		return b.Put(binary.BigEndian.AppendUint64(nil, seq), raw)
+	})
+	if err != nil {
+		return err
+	}
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run handles queued messages until ctx is cancelled. A message is removed only
+// after the handler succeeded.
+func (q *Queue) Run(ctx context.Context) {
+	q.logger.Printf("local queue started")
+	q.running.Store(true)
+	defer q.running.Store(false)
+
+	for {
+		q.lastLoop.Store(time.Now().UnixNano())
+		key, env, err := q.next()
+		if err != nil {
+			q.logger.Printf("local queue read error: %v", err)
+		}
+		if key == nil {
+			select {
+			case <-ctx.Done():
+				q.logger.Printf("local queue stopped")
+				return
+			case <-q.notify:
+			case <-time.After(idleWait):
+			}
+			continue
+		}
+
+		metrics.MessagesReceived.Inc()
+		if err := q.handle(ctx, env); err != nil {
+			if ctx.Err() != nil {
+				q.logger.Printf("local queue stopped")
+				return
+			}
+			q.failed.Add(1)
+			metrics.MessagesFailed.WithLabelValues("handler").Inc()
+			if attempt := q.backoff.Attempt() + 1; attempt >= q.maxAttempts {
+				q.logger.Printf("handler error (attempt %d), moving %s/%s to dead letters: %v", attempt, env.DeviceID, env.TS, err)
+				metrics.MessagesFailed.WithLabelValues("dead_letter").Inc()
+				q.backoff.Reset()
+				if err := q.bury(key); err != nil {
+					q.logger.Printf("local queue dead letter error: %v", err)
+				}
+				continue
+			}
+			d := q.backoff.Next()
+			q.logger.Printf("handler error (attempt %d, retry in %s): %v", q.backoff.Attempt(), d.Round(time.Millisecond), err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(d):
+			}
+			continue
+		}
+
+		q.backoff.Reset()
+		if err := q.db.Update(func(tx *bolt.Tx) error { return tx.Bucket(bucketQueue).Delete(key) }); err != nil {
+			q.logger.Printf("local queue delete error: %v", err)
+		}
+		q.processed.Add(1)
+		metrics.MessagesProcessed.Inc()
+		q.lastProcessed.Store(time.Now().UnixNano())
+	}
+}
+
+// bury moves a message from the queue to the dead bucket under the same key.
+func (q *Queue) bury(key []byte) error {
+	return q.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketQueue)
+		v := b.Get(key)
+		if v == nil {
+			return nil
+		}
+		if err := tx.Bucket(bucketDead).Put(key, v); err != nil {
+			return err
+		}
+		return b.Delete(key)
+	})
+}
+
+// next returns the oldest message. Unreadable entries are dropped.
+func (q *Queue) next() (key []byte, env models.Envelope, err error) {
+	err = q.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketQueue).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.First() {
+			if err := json.Unmarshal(v, &env); err == nil {
+				key = append([]byte(nil), k...)
+				return nil
+			}
+			q.logger.Printf("dropping unreadable queue entry %x", k)
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return key, env, err
+}
+
+// Name identifies the queue in readiness checks.
+func (q *Queue) Name() string {
+	return "local_queue"
+}
+
+func (q *Queue) Activity() queue.Activity {
+	return queue.Activity{
+		Running:         q.running.Load(),
+		LastLoopAt:      unixNano(q.lastLoop.Load()),
+		LastProcessedAt: unixNano(q.lastProcessed.Load()),
+		Processed:       q.processed.Load(),
+		Failed:          q.failed.Load(),
+	}
+}
+
+// Backlog reports the stored messages as visible (there is no in-flight state).
+func (q *Queue) Backlog(_ context.Context) (queue.Backlog, error) {
+	var n int
+	err := q.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(bucketQueue).Stats().KeyN
+		return nil
+	})
+	return queue.Backlog{Visible: n}, err
+}
+
+func unixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
@@ -0,0 +1,135 @@
+package edge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+	bolt "go.etcd.io/bbolt"
+)
+
+var discard = log.New(io.Discard, "", 0)
+
+func openStore(t *testing.T) *repository.BoltStore {
+	t.Helper()
+	st, err := repository.OpenBoltStore(filepath.Join(t.TempDir(), "edge.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+func TestQueueHandlesInOrderAndRetries(t *testing.T) {
+	st := openStore(t)
+
+	var mu sync.Mutex
+	var handled []string
+	fails := 1
+	done := make(chan struct{})
+	q, err := NewQueue(st.DB(), func(_ context.Context, env models.Envelope) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if env.DeviceID == "B" && fails > 0 {
+			fails--
+			return errors.New("transient")
+		}
+		handled = append(handled, env.DeviceID)
+		if len(handled) == 3 {
+			close(done)
+		}
+		return nil
+	}, discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.backoff.Base, q.backoff.Max = time.Millisecond, time.Millisecond
+
+	// accepted before the loop runs, as after a restart
+	for _, dev := range []string{"A", "B", "C"} {
+		if err := q.Enqueue(models.Envelope{DeviceID: dev, TS: "2025-12-03T20:00:00Z"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b, _ := q.Backlog(context.Background()); b.Visible != 3 {
+		t.Fatalf("backlog = %+v", b)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(stopped)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("messages not handled")
+	}
+	cancel()
+	<-stopped
+
+	if got := handled; len(got) != 3 || got[0] != "A" || got[1] != "B" || got[2] != "C" {
+		t.Fatalf("handled = %v", got)
+	}
+	act := q.Activity()
+	if act.Processed != 3 || act.Failed != 1 {
+		t.Fatalf("activity = %+v", act)
+	}
+	if b, _ := q.Backlog(context.Background()); b.Visible != 0 {
+		t.Fatalf("backlog after run = %+v", b)
+	}
+}
+
+func TestQueueMovesPoisonMessageToDead(t *testing.T) {
+	st := openStore(t)
+	handled := make(chan string, 10)
+	q, err := NewQueue(st.DB(), func(_ context.Context, env models.Envelope) error {
+		if env.DeviceID == "A" {
+			return errors.New("permanent")
+		}
+		handled <- env.DeviceID
+		return nil
+	}, discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.backoff.Base, q.backoff.Max = time.Millisecond, time.Millisecond
+	for _, dev := range []string{"A", "B"} {
+		q.Enqueue(models.Envelope{DeviceID: dev, TS: "2025-12-03T20:00:00Z"})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(stopped)
+	}()
+	select {
+	case dev := <-handled:
+		if dev != "B" {
+			t.Fatalf("handled %s", dev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("queue blocked by failing message")
+	}
+	cancel()
+	<-stopped
+
+	if act := q.Activity(); act.Failed != maxAttempts {
+		t.Fatalf("activity = %+v", act)
+	}
+	st.DB().View(func(tx *bolt.Tx) error {
+		if n := tx.Bucket(bucketDead).Stats().KeyN; n != 1 {
+			t.Fatalf("dead letters = %d", n)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,372 @@
+package edge
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/metrics"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+)
+
+// SyncConfig names the cloud resources the gateway uploads to.
+type SyncConfig struct {
+	GatewayID    string
+	AlertsTable  string
+	DevicesTable string
+	Bucket       string
+	AudioDir     string
+	// records per kind per round
+	BatchSize int
+}
+
+// SyncStatus is the state reported by GET /readyz and the logs.
+type SyncStatus struct {
+	Pending       int       `json:"pending"`
+	LastAttemptAt time.Time `json:"lastAttemptAt,omitzero"`
+	LastSuccessAt time.Time `json:"lastSuccessAt,omitzero"`
+	LastError     string    `json:"lastError,omitempty"`
+}
+
+// Syncer uploads what the gateway recorded (outbox of the BoltStore) to
+// DynamoDB and S3 once the uplink works. Every upload is idempotent and a
+// record leaves the outbox only after it was written, so a round cut short by
+// a dropped connection just continues in the next one.
+//
+// Order within a round: sensors, then alerts with their clips (a clip is
+// uploaded before the alert that points to it), then sources.
+type Syncer struct {
+	local   *repository.BoltStore
+	ddb     *dynamodb.Client
+	s3      *s3.Client
+	sources repository.SourceStore
+	cfg     SyncConfig
+	logger  *log.Logger
+
+	mu     sync.Mutex
+	status SyncStatus
+}
+
+func NewSyncer(local *repository.BoltStore, ddb *dynamodb.Client, s3Cli *s3.Client, sources repository.SourceStore, cfg SyncConfig, logger *log.Logger) *Syncer {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	return &Syncer{local: local, ddb: ddb, s3: s3Cli, sources: sources, cfg: cfg, logger: logger}
+}
+
+// Run syncs every interval until ctx is done.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.SyncOnce(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Printf("edge sync: %d uploaded, stopped on: %v", n, err)
+		} else if n > 0 {
+			s.logger.Printf("edge sync: %d uploaded, %d pending", n, s.local.PendingCount())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncOnce uploads pending records until the outbox is empty or an upload
+// fails, and returns how many were uploaded.
+func (s *Syncer) SyncOnce(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	s.status.LastAttemptAt = time.Now().UTC()
+	s.mu.Unlock()
+
+	total := 0
+	var err error
+	for _, kind := range []string{repository.OutboxSensor, repository.OutboxAlert, repository.OutboxSource} {
+		var n int
+		n, err = s.syncKind(ctx, kind)
+		total += n
+		if err != nil {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	if err != nil {
+		s.status.LastError = err.Error()
+	} else {
+		s.status.LastError = ""
+		s.status.LastSuccessAt = time.Now().UTC()
+	}
+	s.mu.Unlock()
+	return total, err
+}
+
+func (s *Syncer) syncKind(ctx context.Context, kind string) (int, error) {
+	n := 0
+	for {
+		pending, err := s.local.Pending(kind, s.cfg.BatchSize)
+		if err != nil || len(pending) == 0 {
+			return n, err
+		}
+		for _, e := range pending {
+			if err := s.upload(ctx, e); err != nil {
+				return n, err
+			}
+			if err := s.local.Ack(e); err != nil {
+				return n, err
+			}
+			metrics.EdgeSynced.WithLabelValues(kind).Inc()
+			n++
+		}
+		if len(pending) < s.cfg.BatchSize {
+			return n, nil
+		}
+	}
+}
+
+// Status returns the last sync outcome and the current outbox size.
+func (s *Syncer) Status() SyncStatus {
+	s.mu.Lock()
+	st := s.status
+	s.mu.Unlock()
+	st.Pending = s.local.PendingCount()
+	return st
+}
+
+func (s *Syncer) upload(ctx context.Context, e repository.OutboxEntry) error {
+	switch e.Kind {
+	case repository.OutboxAlert:
+		a, err := s.local.GetAlertsByKeys(ctx, []string{e.ID})
+		if err != nil || len(a) == 0 {
+			return err
+		}
+		return s.uploadAlert(ctx, a[0])
+	case repository.OutboxSensor:
+		sn, err := s.local.GetSensor(ctx, e.ID)
+		if err != nil || sn == nil {
+			return err
+		}
+		return s.uploadSensor(ctx, *sn)
+	case repository.OutboxSource:
+		if s.sources == nil {
+			// no sources_table configured: sources stay on the gateway
+			return nil
+		}
+		src, err := s.local.GetSource(ctx, e.ID)
+		if err != nil || src == nil {
+			return err
+		}
+		// sources are keyed by a random id, a plain put is idempotent
+		return s.sources.SaveSource(ctx, *src)
+	}
+	return nil
+}
+
+func (s *Syncer) uploadAlert(ctx context.Context, a models.Alert) error {
+	if err := s.uploadAudio(ctx, a); err != nil {
+		return err
+	}
+
+	a.Origin = s.cfg.GatewayID
+	item, err := attributevalue.MarshalMap(a)
+	if err != nil {
+		return err
+	}
+	item["tsDay"] = &types.AttributeValueMemberS{Value: repository.DayBucket(a.TS)}
+	_, err = s.ddb.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(s.cfg.AlertsTable),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(deviceId)"),
+	})
+	var ccf *types.ConditionalCheckFailedException
+	if !errors.As(err, &ccf) {
+		return err
+	}
+
+	// the key is already in the cloud: an earlier round that was not acked,
+	// or an operator changed the status on both sides
+	out, err := s.ddb.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.cfg.AlertsTable),
+		Key:            alertKey(a),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return err
+	}
+	var remote models.Alert
+	if err := attributevalue.UnmarshalMap(out.Item, &remote); err != nil {
+		return err
+	}
+	winner, localWins := resolveAlert(a, remote)
+	if !localWins {
+		if winner.UpdatedAt != a.UpdatedAt || winner.Status != a.Status {
+			metrics.EdgeConflicts.WithLabelValues("cloud").Inc()
+			return s.local.StoreSynced(winner)
+		}
+		return nil
+	}
+
+	metrics.EdgeConflicts.WithLabelValues("gateway").Inc()
+	history, err := attributevalue.Marshal(winner.History)
+	if err != nil {
+		return err
+	}
+	cond := "attribute_not_exists(updatedAt)"
+	values := map[string]types.AttributeValue{
+		":s": &types.AttributeValueMemberS{Value: winner.Status},
+		":h": history,
+		":u": &types.AttributeValueMemberS{Value: winner.UpdatedAt},
+	}
+	if remote.UpdatedAt != "" {
+		cond = "updatedAt = :prev"
+		values[":prev"] = &types.AttributeValueMemberS{Value: remote.UpdatedAt}
+	}
+	update := "SET #s = :s, history = :h, updatedAt = :u"
+	if winner.SourceID != "" {
+		update += ", sourceId = :src"
+		values[":src"] = &types.AttributeValueMemberS{Value: winner.SourceID}
+	}
+	if winner.TTL > 0 {
+		update += ", #ttl = :ttl"
+		values[":ttl"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(winner.TTL, 10)}
+	} else {
+		update += " REMOVE #ttl"
+	}
+	_, err = s.ddb.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(s.cfg.AlertsTable),
+		Key:                       alertKey(a),
+		ConditionExpression:       aws.String(cond),
+		UpdateExpression:          aws.String(update),
+		ExpressionAttributeNames:  map[string]string{"#s": "status", "#ttl": "ttl"},
+		ExpressionAttributeValues: values,
+	})
+	if errors.As(err, &ccf) {
+		// changed in the cloud meanwhile; resolve again next round
+		return errors.New("alert " + a.Key() + " changed in the cloud during sync")
+	}
+	return err
+}
+
+// resolveAlert decides a sync conflict on one alert key. Only the workflow
+// state (status, history, retention) can differ; the copy whose status changed last wins,
+// a tie keeps the cloud copy.
+func resolveAlert(local, remote models.Alert) (models.Alert, bool) {
+	if local.UpdatedAt > remote.UpdatedAt {
+		winner := remote
+		winner.Status, winner.History, winner.UpdatedAt = local.Status, local.History, local.UpdatedAt
+		// retention follows the status and the gateway's sources
+		winner.SourceID, winner.TTL = local.SourceID, local.TTL
+		return winner, true
+	}
+	return remote, false
+}
+
+func (s *Syncer) uploadAudio(ctx context.Context, a models.Alert) error {
+	if a.S3Key == "" {
+		return nil
+	}
+	data, err := os.ReadFile(AudioPath(s.cfg.AudioDir, a.S3Key))
+	if errors.Is(err, os.ErrNotExist) {
+		s.logger.Printf("edge sync: clip %s missing, syncing alert without audio", a.S3Key)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	// same object as lambda-alert would write; a retried put replaces it
+	_, err = s.s3.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(s.cfg.Bucket),
+		Key:                  aws.String(a.S3Key),
+		Body:                 bytes.NewReader(data),
+		ContentType:          aws.String("audio/wav"),
+		ChecksumSHA256:       aws.String(base64.StdEncoding.EncodeToString(sum[:])),
+		ServerSideEncryption: s3types.ServerSideEncryptionAes256,
+		Metadata: map[string]string{
+			"deviceId": a.DeviceID,
+			"ts":       a.TS,
+			"origin":   s.cfg.GatewayID,
+		},
+	})
+	return err
+}
+
+// uploadSensor registers the sensor if the cloud does not know it and moves
+// lastSeen / lastHeartbeat forward only, so an old gateway copy never rewinds
+// what the cloud got directly.
+func (s *Syncer) uploadSensor(ctx context.Context, sn models.Sensor) error {
+	key := map[string]types.AttributeValue{"deviceId": &types.AttributeValueMemberS{Value: sn.DeviceID}}
+	update := func(expr, cond string, values map[string]types.AttributeValue) error {
+		in := &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(s.cfg.DevicesTable),
+			Key:                       key,
+			UpdateExpression:          aws.String(expr),
+			ExpressionAttributeValues: values,
+		}
+		if cond != "" {
+			in.ConditionExpression = aws.String(cond)
+		}
+		_, err := s.ddb.UpdateItem(ctx, in)
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return nil
+		}
+		return err
+	}
+
+	err := update("SET firstSeen = if_not_exists(firstSeen, :fs), lat = if_not_exists(lat, :lat), lon = if_not_exists(lon, :lon)", "",
+		map[string]types.AttributeValue{
+			":fs":  &types.AttributeValueMemberS{Value: sn.FirstSeen.UTC().Format(time.RFC3339)},
+			":lat": &types.AttributeValueMemberN{Value: strconv.FormatFloat(sn.Lat, 'f', -1, 64)},
+			":lon": &types.AttributeValueMemberN{Value: strconv.FormatFloat(sn.Lon, 'f', -1, 64)},
+		})
+	if err != nil {
+		return err
+	}
+	if !sn.LastSeen.IsZero() {
+		err = update("SET lastSeen = :ls", "attribute_not_exists(lastSeen) OR lastSeen < :ls",
+			map[string]types.AttributeValue{":ls": &types.AttributeValueMemberS{Value: sn.LastSeen.UTC().Format(time.RFC3339)}})
+		if err != nil {
+			return err
+		}
+	}
+	if !sn.LastHeartbeat.IsZero() {
+		expr := "SET lastHeartbeat = :hb"
+		values := map[string]types.AttributeValue{":hb": &types.AttributeValueMemberS{Value: sn.LastHeartbeat.UTC().Format(time.RFC3339)}}
+		if sn.Telemetry != nil {
+			tel, err := attributevalue.MarshalMap(sn.Telemetry)
+			if err != nil {
+				return err
+			}
+			expr += ", telemetry = :t"
+			values[":t"] = &types.AttributeValueMemberM{Value: tel}
+		}
+		return update(expr, "attribute_not_exists(lastHeartbeat) OR lastHeartbeat < :hb", values)
+	}
+	return nil
+}
+
+func alertKey(a models.Alert) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"deviceId": &types.AttributeValueMemberS{Value: a.DeviceID},
+		"ts":       &types.AttributeValueMemberS{Value: a.TS},
+	}
+}
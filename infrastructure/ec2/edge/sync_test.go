@@ -0,0 +1,29 @@
+package edge
+
+import (
+	"testing"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+func TestResolveAlert(t *testing.T) {
+	remote := models.Alert{DeviceID: "A", TS: "2025-12-03T20:00:00Z", Status: models.StatusAcknowledged, UpdatedAt: "2025-12-03T20:05:00Z", Origin: "gw-1"}
+
+	// status changed on the gateway after the cloud copy
+	local := remote
+	local.Status, local.UpdatedAt = models.StatusResolved, "2025-12-03T20:10:00Z"
+	local.History = []models.StatusChange{{From: models.StatusAcknowledged, To: models.StatusResolved, At: local.UpdatedAt}}
+	winner, localWins := resolveAlert(local, remote)
+	if !localWins || winner.Status != models.StatusResolved || len(winner.History) != 1 || winner.Origin != "gw-1" {
+		t.Fatalf("newer local: %+v, %v", winner, localWins)
+	}
+
+	// cloud changed later, or both untouched since the first upload
+	local.UpdatedAt = "2025-12-03T20:01:00Z"
+	if winner, localWins := resolveAlert(local, remote); localWins || winner.Status != models.StatusAcknowledged {
+		t.Fatalf("newer remote: %+v, %v", winner, localWins)
+	}
+	if _, localWins := resolveAlert(remote, remote); localWins {
+		t.Fatal("tie must keep the cloud copy")
+	}
+}
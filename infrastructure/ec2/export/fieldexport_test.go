@@ -0,0 +1,61 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+)
+
+func testSources() []processor.SourceGroup {
+	return []processor.SourceGroup{{
+		ID:        "src-1",
+		Lat:       50.0601,
+		Lon:       19.9401,
+		UpdatedAt: time.Date(2025, 12, 3, 20, 30, 0, 0, time.UTC),
+		Alerts: []*models.Alert{
+			{DeviceID: "A", TS: "2025-12-03T20:27:14Z", Lat: 50.06, Lon: 19.94, Distance: 20},
+			{DeviceID: "B", TS: "2025-12-03T20:27:15Z", Lat: 50.061, Lon: 19.941, Distance: 120},
+		},
+	}}
+}
+
+func TestWriteSourcesKML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSourcesKML(&buf, "sources", testSources()); err != nil {
+		t.Fatal(err)
+	}
+	var root kmlRoot
+	if err := xml.Unmarshal(buf.Bytes(), &root); err != nil {
+		t.Fatalf("invalid KML: %v\n%s", err, buf.String())
+	}
+	if len(root.Document.Folders) != 1 {
+		t.Fatalf("folders = %d", len(root.Document.Folders))
+	}
+	// source point + uncertainty + 2 x (sensor + circle)
+	pms := root.Document.Folders[0].Placemarks
+	if len(pms) != 6 {
+		t.Fatalf("placemarks = %d", len(pms))
+	}
+	if pms[0].Point == nil || pms[0].Point.Coordinates != "19.9401000,50.0601000,0" || pms[0].StyleURL != "#source" {
+		t.Fatalf("source placemark = %+v", pms[0])
+	}
+	if pms[3].Polygon == nil || pms[3].StyleURL != "#detection" {
+		t.Fatalf("detection placemark = %+v", pms[3])
+	}
+}
+
+func TestWriteSourcesGPX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSourcesGPX(&buf, "sources", testSources(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `<wpt lat="50.0601" lon="19.9401">`) || !strings.Contains(out, "<name>src-1</name>") {
+		t.Fatalf("unexpected GPX:\n%s", out)
+	}
+}
@@ -0,0 +1,187 @@
+package export
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+)
+
+const GeoJSONContentType = "application/geo+json"
+
+// number of vertices used to approximate circles as polygons
+const circleSegments = 64
+
+type FeatureCollection struct {
+	Type       string         `json:"type"`
+	Features   []Feature      `json:"features"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+	Properties map[string]any `json:"properties,omitempty"`
+}
+
+type Feature struct {
+	Type       string         `json:"type"`
+	ID         string         `json:"id,omitempty"`
+	Geometry   Geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type Geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+func NewFeatureCollection(features []Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// Point builds a GeoJSON point; note GeoJSON uses lon, lat order.
+func Point(lat, lon float64) Geometry {
+	return Geometry{Type: "Point", Coordinates: []float64{lon, lat}}
+}
+
+// Circle approximates a circle of radius meters around lat/lon as a polygon.
+func Circle(lat, lon, radius float64) Geometry {
+	ring := CircleRing(lat, lon, radius, circleSegments)
+	coords := make([][]float64, len(ring))
+	for i, p := range ring {
+		coords[i] = []float64{p[1], p[0]}
+	}
+	return Geometry{Type: "Polygon", Coordinates: [][][]float64{coords}}
+}
+
+// CircleRing returns a closed ring of [lat, lon] points on a circle of radius
+// meters, using the destination-point formula on a sphere. The ring runs
+// counter-clockwise, as RFC 7946 requires for exterior rings.
+func CircleRing(lat, lon, radius float64, segments int) [][2]float64 {
+	const R = 6371e3
+	phi1 := lat * math.Pi / 180
+	lambda1 := lon * math.Pi / 180
+	delta := radius / R
+
+	ring := make([][2]float64, 0, segments+1)
+	for i := 0; i < segments; i++ {
+		// bearings grow clockwise, so walk them backwards
+		theta := -2 * math.Pi * float64(i) / float64(segments)
+		phi2 := math.Asin(math.Sin(phi1)*math.Cos(delta) + math.Cos(phi1)*math.Sin(delta)*math.Cos(theta))
+		lambda2 := lambda1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(phi1), math.Cos(delta)-math.Sin(phi1)*math.Sin(phi2))
+		ring = append(ring, [2]float64{phi2 * 180 / math.Pi, lambda2 * 180 / math.Pi})
+	}
+	return append(ring, ring[0])
+}
+
+func SensorsGeoJSON(sensors []models.Sensor) FeatureCollection {
+	features := make([]Feature, 0, len(sensors))
+	for _, s := range sensors {
+		props := properties(s, "lat", "lon")
+		props["kind"] = "sensor"
+		features = append(features, Feature{
+			Type:       "Feature",
+			ID:         s.DeviceID,
+			Geometry:   Point(s.Lat, s.Lon),
+			Properties: props,
+		})
+	}
+	return NewFeatureCollection(features)
+}
+
+// AlertsGeoJSON emits, per alert, a point at the sensor and a polygon of its
+// detection radius.
+func AlertsGeoJSON(alerts []models.Alert) FeatureCollection {
+	features := make([]Feature, 0, 2*len(alerts))
+	for _, a := range alerts {
+		props := properties(a, "lat", "lon", "history")
+		props["kind"] = "alert"
+		features = append(features, Feature{
+			Type:       "Feature",
+			ID:         a.Key(),
+			Geometry:   Point(a.Lat, a.Lon),
+			Properties: props,
+		})
+		if a.Distance > 0 {
+			radius := properties(a, "lat", "lon", "history")
+			radius["kind"] = "detection_radius"
+			features = append(features, Feature{
+				Type:       "Feature",
+				ID:         a.Key() + "/radius",
+				Geometry:   Circle(a.Lat, a.Lon, a.Distance),
+				Properties: radius,
+			})
+		}
+	}
+	return NewFeatureCollection(features)
+}
+
+// SourcesGeoJSON emits, per source, the estimated position and a polygon of its
+// uncertainty area. Contributing alerts are referenced by key.
+func SourcesGeoJSON(sources []processor.SourceGroup) FeatureCollection {
+	features := make([]Feature, 0, 2*len(sources))
+	for _, sg := range sources {
+		keys := make([]string, 0, len(sg.Alerts))
+		devices := make([]string, 0, len(sg.Alerts))
+		for _, a := range sg.Alerts {
+			if a != nil {
+				keys = append(keys, a.Key())
+				devices = append(devices, a.DeviceID)
+			}
+		}
+		uncertainty := sg.UncertaintyRadius()
+
+		props := properties(sg, "lat", "lon", "alerts")
+		props["kind"] = "source"
+		props["alertKeys"] = keys
+		props["deviceIds"] = devices
+		props["alertCount"] = len(keys)
+		props["uncertaintyM"] = uncertainty
+		features = append(features, Feature{
+			Type:       "Feature",
+			ID:         sg.ID,
+			Geometry:   Point(sg.Lat, sg.Lon),
+			Properties: props,
+		})
+
+		area := map[string]any{"kind": "uncertainty", "sourceId": sg.ID, "uncertaintyM": uncertainty}
+		features = append(features, Feature{
+			Type:       "Feature",
+			ID:         sg.ID + "/uncertainty",
+			Geometry:   Circle(sg.Lat, sg.Lon, uncertainty),
+			Properties: area,
+		})
+	}
+	return NewFeatureCollection(features)
+}
+
+// StoredSourcesGeoJSON emits points for sources read from the source store.
+func StoredSourcesGeoJSON(sources []models.Source) FeatureCollection {
+	features := make([]Feature, 0, len(sources))
+	for _, s := range sources {
+		props := properties(s, "lat", "lon")
+		props["kind"] = "source"
+		props["alertCount"] = len(s.AlertKeys)
+		features = append(features, Feature{
+			Type:       "Feature",
+			ID:         s.ID,
+			Geometry:   Point(s.Lat, s.Lon),
+			Properties: props,
+		})
+	}
+	return NewFeatureCollection(features)
+}
+
+// properties turns v into a map using its JSON field names, dropping omit.
+func properties(v any, omit ...string) map[string]any {
+	props := map[string]any{}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return props
+	}
+	_ = json.Unmarshal(b, &props)
+	for _, k := range omit {
+		delete(props, k)
+	}
+	return props
+}
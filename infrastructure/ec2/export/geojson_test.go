@@ -0,0 +1,53 @@
+package export
+
+import (
+	"math"
+	"testing"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+)
+
+func TestCircleRing(t *testing.T) {
+	lat, lon, radius := 50.06, 19.93, 500.0
+	ring := CircleRing(lat, lon, radius, 32)
+	if len(ring) != 33 || ring[0] != ring[32] {
+		t.Fatalf("ring not closed: len=%d", len(ring))
+	}
+	for _, p := range ring {
+		if d := processor.Haversine(lat, lon, p[0], p[1]); math.Abs(d-radius) > 0.5 {
+			t.Fatalf("vertex %v at %.2f m, want %.0f m", p, d, radius)
+		}
+	}
+	// shoelace over (lon, lat): positive area = counter-clockwise
+	area := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		area += ring[i][1]*ring[i+1][0] - ring[i+1][1]*ring[i][0]
+	}
+	if area <= 0 {
+		t.Fatalf("exterior ring is clockwise (area %g)", area)
+	}
+}
+
+func TestAlertsGeoJSON(t *testing.T) {
+	fc := AlertsGeoJSON([]models.Alert{
+		{DeviceID: "A", TS: "t1", Lat: 50, Lon: 19, Distance: 300, Status: models.StatusNew},
+		{DeviceID: "B", TS: "t1", Lat: 50.01, Lon: 19.01},
+	})
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 3 {
+		t.Fatalf("features = %d", len(fc.Features))
+	}
+	pt := fc.Features[0]
+	if pt.Geometry.Type != "Point" || pt.Properties["deviceId"] != "A" || pt.Properties["status"] != "NEW" {
+		t.Fatalf("point feature = %+v", pt)
+	}
+	if c := pt.Geometry.Coordinates.([]float64); c[0] != 19 || c[1] != 50 {
+		t.Fatalf("coordinates not lon,lat: %v", c)
+	}
+	if _, ok := pt.Properties["lat"]; ok {
+		t.Fatal("lat duplicated in properties")
+	}
+	if fc.Features[1].Geometry.Type != "Polygon" || fc.Features[1].Properties["kind"] != "detection_radius" {
+		t.Fatalf("radius feature = %+v", fc.Features[1])
+	}
+}
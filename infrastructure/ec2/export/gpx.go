@@ -0,0 +1,72 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"time"
+
+	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+)
+
+const GPXContentType = "application/gpx+xml"
+
+type gpxRoot struct {
+	XMLName   xml.Name      `xml:"gpx"`
+	NS        string        `xml:"xmlns,attr"`
+	Version   string        `xml:"version,attr"`
+	Creator   string        `xml:"creator,attr"`
+	Metadata  gpxMetadata   `xml:"metadata"`
+	Waypoints []gpxWaypoint `xml:"wpt"`
+}
+
+type gpxMetadata struct {
+	Name string `xml:"name"`
+	Time string `xml:"time"`
+}
+
+type gpxWaypoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time,omitempty"`
+	Name string  `xml:"name"`
+	Desc string  `xml:"desc,omitempty"`
+	Sym  string  `xml:"sym"`
+	Type string  `xml:"type"`
+}
+
+// WriteSourcesGPX writes one waypoint per source. Handheld units only show
+// points, so uncertainty and contributing sensors go into the description.
+func WriteSourcesGPX(w io.Writer, name string, sources []processor.SourceGroup, now time.Time) error {
+	root := gpxRoot{
+		NS:       "http://www.topografix.com/GPX/1/1",
+		Version:  "1.1",
+		Creator:  "sound-based-forest-monitoring",
+		Metadata: gpxMetadata{Name: name, Time: kmlTime(now)},
+	}
+	for _, sg := range sources {
+		root.Waypoints = append(root.Waypoints, gpxWaypoint{
+			Lat:  round7(sg.Lat),
+			Lon:  round7(sg.Lon),
+			Time: kmlTime(sg.UpdatedAt),
+			Name: sg.ID,
+			Desc: sourceDescription(sg, sg.UncertaintyRadius()),
+			Sym:  "Danger Area",
+			Type: "source",
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func round7(v float64) float64 {
+	return math.Round(v*1e7) / 1e7
+}
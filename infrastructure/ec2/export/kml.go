@@ -0,0 +1,179 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+)
+
+const KMLContentType = "application/vnd.google-earth.kml+xml"
+
+// KML colours are aabbggrr.
+var kmlStyles = []kmlStyle{
+	{ID: "source", Icon: &kmlIconStyle{Color: "ff0000ff", Scale: 1.3, Href: "http://maps.google.com/mapfiles/kml/shapes/caution.png"}},
+	{ID: "sensor", Icon: &kmlIconStyle{Color: "ff00ff00", Scale: 0.8, Href: "http://maps.google.com/mapfiles/kml/shapes/placemark_circle.png"}},
+	{ID: "detection", Line: &kmlLineStyle{Color: "ff0000ff", Width: 1.5}, Poly: &kmlPolyStyle{Color: "200000ff"}},
+	{ID: "uncertainty", Line: &kmlLineStyle{Color: "ffff0000", Width: 2}, Poly: &kmlPolyStyle{Color: "40ff0000"}},
+}
+
+type kmlRoot struct {
+	XMLName  xml.Name    `xml:"kml"`
+	NS       string      `xml:"xmlns,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name    string      `xml:"name"`
+	Styles  []kmlStyle  `xml:"Style"`
+	Folders []kmlFolder `xml:"Folder"`
+}
+
+type kmlStyle struct {
+	ID   string        `xml:"id,attr"`
+	Icon *kmlIconStyle `xml:"IconStyle,omitempty"`
+	Line *kmlLineStyle `xml:"LineStyle,omitempty"`
+	Poly *kmlPolyStyle `xml:"PolyStyle,omitempty"`
+}
+
+type kmlIconStyle struct {
+	Color string  `xml:"color"`
+	Scale float64 `xml:"scale"`
+	Href  string  `xml:"Icon>href"`
+}
+
+type kmlLineStyle struct {
+	Color string  `xml:"color"`
+	Width float64 `xml:"width"`
+}
+
+type kmlPolyStyle struct {
+	Color string `xml:"color"`
+}
+
+type kmlFolder struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string      `xml:"name"`
+	Description string      `xml:"description,omitempty"`
+	TimeStamp   string      `xml:"TimeStamp>when,omitempty"`
+	StyleURL    string      `xml:"styleUrl"`
+	Point       *kmlPoint   `xml:"Point,omitempty"`
+	Polygon     *kmlPolygon `xml:"Polygon,omitempty"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlPolygon struct {
+	Coordinates string `xml:"outerBoundaryIs>LinearRing>coordinates"`
+}
+
+// WriteSourcesKML writes one folder per source: the estimated position, its
+// uncertainty area, and the contributing sensors with their detection circles.
+func WriteSourcesKML(w io.Writer, name string, sources []processor.SourceGroup) error {
+	doc := kmlDocument{Name: name, Styles: kmlStyles, Folders: []kmlFolder{}}
+	for _, sg := range sources {
+		uncertainty := sg.UncertaintyRadius()
+		folder := kmlFolder{Name: sg.ID}
+		folder.Placemarks = append(folder.Placemarks,
+			kmlPlacemark{
+				Name:        sg.ID,
+				Description: sourceDescription(sg, uncertainty),
+				TimeStamp:   kmlTime(sg.UpdatedAt),
+				StyleURL:    "#source",
+				Point:       &kmlPoint{Coordinates: kmlCoord(sg.Lat, sg.Lon)},
+			},
+			kmlPlacemark{
+				Name:     sg.ID + " uncertainty",
+				StyleURL: "#uncertainty",
+				Polygon:  &kmlPolygon{Coordinates: kmlRing(sg.Lat, sg.Lon, uncertainty)},
+			},
+		)
+		for _, a := range sg.Alerts {
+			if a == nil {
+				continue
+			}
+			folder.Placemarks = append(folder.Placemarks, kmlPlacemark{
+				Name:        a.DeviceID,
+				Description: alertDescription(a),
+				TimeStamp:   a.TS,
+				StyleURL:    "#sensor",
+				Point:       &kmlPoint{Coordinates: kmlCoord(a.Lat, a.Lon)},
+			})
+			if a.Distance > 0 {
+				folder.Placemarks = append(folder.Placemarks, kmlPlacemark{
+					Name:     fmt.Sprintf("%s %.0f m", a.DeviceID, a.Distance),
+					StyleURL: "#detection",
+					Polygon:  &kmlPolygon{Coordinates: kmlRing(a.Lat, a.Lon, a.Distance)},
+				})
+			}
+		}
+		doc.Folders = append(doc.Folders, folder)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(kmlRoot{NS: "http://www.opengis.net/kml/2.2", Document: doc}); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func kmlCoord(lat, lon float64) string {
+	return fmt.Sprintf("%.7f,%.7f,0", lon, lat)
+}
+
+func kmlRing(lat, lon, radius float64) string {
+	ring := CircleRing(lat, lon, radius, circleSegments)
+	parts := make([]string, len(ring))
+	for i, p := range ring {
+		parts[i] = kmlCoord(p[0], p[1])
+	}
+	return strings.Join(parts, " ")
+}
+
+func kmlTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func sourceDescription(sg processor.SourceGroup, uncertainty float64) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Position: %.6f, %.6f (±%.0f m)\n", sg.Lat, sg.Lon, uncertainty)
+	if !sg.FirstSeen.IsZero() {
+		fmt.Fprintf(&b, "First seen: %s\n", kmlTime(sg.FirstSeen))
+	}
+	if !sg.UpdatedAt.IsZero() {
+		fmt.Fprintf(&b, "Updated: %s\n", kmlTime(sg.UpdatedAt))
+	}
+	devices := make([]string, 0, len(sg.Alerts))
+	for _, a := range sg.Alerts {
+		if a != nil {
+			devices = append(devices, a.DeviceID)
+		}
+	}
+	fmt.Fprintf(&b, "Sensors (%d): %s", len(devices), strings.Join(devices, ", "))
+	return b.String()
+}
+
+func alertDescription(a *models.Alert) string {
+	desc := fmt.Sprintf("Alert %s\nDistance: %.0f m\nStatus: %s", a.TS, a.Distance, a.Status)
+	if a.Class != "" {
+		desc += "\nClass: " + a.Class
+	}
+	return desc
+}
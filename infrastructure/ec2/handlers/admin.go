@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/api"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/queue"
+)
+
+// AdminHandler exposes the dead-letter and quarantine queues for inspection,
+// redrive and purge. Queues are addressed by name ("dlq", "quarantine").
+type AdminHandler struct {
+	queues map[string]*queue.DLQ
+	logger *log.Logger
+}
+
+func NewAdminHandler(queues map[string]*queue.DLQ, logger *log.Logger) *AdminHandler {
+	return &AdminHandler{queues: queues, logger: logger}
+}
+
+func (h *AdminHandler) queue(c *gin.Context) *queue.DLQ {
+	q, ok := h.queues[c.Param("queue")]
+	if !ok || q == nil {
+		c.JSON(404, gin.H{"error": "unknown queue"})
+		return nil
+	}
+	return q
+}
+
+func (h *AdminHandler) ListMessages(c *gin.Context) {
+	q := h.queue(c)
+	if q == nil {
+		return
+	}
+	max := 10
+	if v := c.Query("max"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 100 {
+			c.JSON(400, gin.H{"error": "max must be between 1 and 100"})
+			return
+		}
+		max = n
+	}
+
+	msgs, err := q.List(c.Request.Context(), max)
+	if err != nil {
+		h.logger.Printf("dlq list error: %v", err)
+		c.JSON(500, gin.H{"error": "internal server error"})
+		return
+	}
+	c.JSON(200, api.DeadLetterList{Count: len(msgs), Messages: msgs})
+}
+
+func (h *AdminHandler) GetMessage(c *gin.Context) {
+	q := h.queue(c)
+	if q == nil {
+		return
+	}
+	m, err := q.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondErr(c, "dlq get", err)
+		return
+	}
+	c.JSON(200, m)
+}
+
+func (h *AdminHandler) RedriveMessage(c *gin.Context) {
+	q := h.queue(c)
+	if q == nil {
+		return
+	}
+	id := c.Param("id")
+	if err := q.Redrive(c.Request.Context(), id); err != nil {
+		h.respondErr(c, "dlq redrive", err)
+		return
+	}
+	h.logger.Printf("redriven message %s from %s", id, c.Param("queue"))
+	c.JSON(200, api.Redriven{Redriven: id})
+}
+
+func (h *AdminHandler) PurgeMessage(c *gin.Context) {
+	q := h.queue(c)
+	if q == nil {
+		return
+	}
+	id := c.Param("id")
+	if err := q.Purge(c.Request.Context(), id); err != nil {
+		h.respondErr(c, "dlq purge", err)
+		return
+	}
+	h.logger.Printf("purged message %s from %s", id, c.Param("queue"))
+	c.JSON(200, api.Purged{Purged: id})
+}
+
+func (h *AdminHandler) PurgeQueue(c *gin.Context) {
+	q := h.queue(c)
+	if q == nil {
+		return
+	}
+	if err := q.Purge(c.Request.Context(), ""); err != nil {
+		h.respondErr(c, "dlq purge", err)
+		return
+	}
+	h.logger.Printf("purged queue %s", c.Param("queue"))
+	c.JSON(200, api.Purged{Purged: c.Param("queue")})
+}
+
+func (h *AdminHandler) respondErr(c *gin.Context, op string, err error) {
+	switch {
+	case errors.Is(err, queue.ErrMessageNotFound):
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, queue.ErrMessageUnreachable):
+		c.JSON(409, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Printf("%s error: %v", op, err)
+	c.JSON(500, gin.H{"error": "internal server error"})
+}
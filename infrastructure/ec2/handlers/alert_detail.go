@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/api"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+)
+
+// GetAlert handles GET /alerts/:deviceId/:ts.
+func (h *Handler) GetAlert(c *gin.Context) {
+	ctx := c.Request.Context()
+	deviceID, ts := c.Param("deviceId"), c.Param("ts")
+
+	a, err := h.alerts.GeAlertByPK(ctx, deviceID, ts, false)
+	if err != nil {
+		h.logger.Printf("GeAlertByPK error: %v", err)
+		c.JSON(500, gin.H{"error": "internal server error"})
+		return
+	}
+	if a == nil {
+		c.JSON(404, gin.H{"error": "alert not found"})
+		return
+	}
+
+	resp := api.AlertDetail{
+		Alert:   *a,
+		Derived: []api.MediaLink{},
+		Sources: h.sources.List(func(sg processor.SourceGroup) bool {
+			for _, sa := range sg.Alerts {
+				if sa != nil && sa.DeviceID == deviceID && sa.TS == ts {
+					return true
+				}
+			}
+			return false
+		}),
+	}
+
+	if a.S3Key != "" && h.audio.Enabled() {
+		proxied := *a
+		h.linkAudio(&proxied)
+		resp.Audio = &api.MediaLink{Key: a.S3Key, Kind: "audio", URL: proxied.AudioURL}
+
+		derived, err := h.audio.ListDerived(ctx, a.S3Key)
+		if err != nil {
+			h.logger.Printf("ListDerived error for key %s: %v", a.S3Key, err)
+		}
+		for _, d := range derived {
+			resp.Derived = append(resp.Derived, api.MediaLink{Key: d.Key, Kind: d.Kind, URL: h.mediaURL(*a, d.Kind), Size: d.Size})
+		}
+	}
+
+	h.linkAudio(&resp.Alert)
+	for i := range resp.Sources {
+		for _, sa := range resp.Sources[i].Alerts {
+			if sa != nil {
+				h.linkAudio(sa)
+			}
+		}
+	}
+
+	c.JSON(200, resp)
+}
+
+// mediaURL points at the media proxy, so every download of derived media goes
+// through the role check and the audit log like the clip itself.
+func (h *Handler) mediaURL(a models.Alert, kind string) string {
+	return h.publicBaseURL + "/alerts/" + url.PathEscape(a.DeviceID) + "/" + url.PathEscape(a.TS) + "/media/" + url.PathEscape(kind)
+}
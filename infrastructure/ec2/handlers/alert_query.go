@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+)
+
+// maxAlertRange caps to-from of GET /alerts: every day of the range is a byDay
+// partition to query. Older alerts are archived to S3 by retention anyway.
+const maxAlertRange = 180 * 24 * time.Hour
+
+// parseAlertQuery reads the GET /alerts query parameters:
+//
+//	from, to   RFC3339 timestamps (default: the last hour, at most maxAlertRange apart)
+//	deviceId   single sensor
+//	status     comma separated statuses
+//	class      comma separated sound classes
+//	bbox       minLon,minLat,maxLon,maxLat
+//	limit      page size (max repository.MaxPageSize)
+//	cursor     nextCursor of the previous page
+func parseAlertQuery(c *gin.Context) (repository.AlertQuery, error) {
+	var q repository.AlertQuery
+	var err error
+
+	if q.From, err = parseTime(c.Query("from")); err != nil {
+		return q, fmt.Errorf("invalid from: %w", err)
+	}
+	if q.To, err = parseTime(c.Query("to")); err != nil {
+		return q, fmt.Errorf("invalid to: %w", err)
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && q.From.After(q.To) {
+		return q, errors.New("from must be before to")
+	}
+	if !q.From.IsZero() && q.To.IsZero() {
+		q.To = time.Now().UTC()
+	}
+	if !q.From.IsZero() && q.To.Sub(q.From) > maxAlertRange {
+		return q, fmt.Errorf("range longer than %s", maxAlertRange)
+	}
+
+	q.DeviceID = strings.TrimSpace(c.Query("deviceId"))
+	for _, cl := range splitList(c.Query("class")) {
+		q.Classes = append(q.Classes, strings.ToLower(cl))
+	}
+	q.Cursor = c.Query("cursor")
+
+	for _, s := range splitList(c.Query("status")) {
+		s = strings.ToUpper(s)
+		if !models.ValidStatus(s) {
+			return q, fmt.Errorf("invalid status %q", s)
+		}
+		q.Statuses = append(q.Statuses, s)
+	}
+
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > repository.MaxPageSize {
+			return q, fmt.Errorf("limit must be between 1 and %d", repository.MaxPageSize)
+		}
+		q.Limit = n
+	}
+
+	if v := c.Query("bbox"); v != "" {
+		bbox, err := parseBBox(v)
+		if err != nil {
+			return q, err
+		}
+		q.BBox = &bbox
+	}
+
+	return q, nil
+}
+
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func splitList(v string) []string {
+	var res []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// parseBBox parses "minLon,minLat,maxLon,maxLat" (GeoJSON order).
+func parseBBox(v string) (models.BBox, error) {
+	parts := strings.Split(v, ",")
+	if len(parts) != 4 {
+		return models.BBox{}, errors.New("bbox must be minLon,minLat,maxLon,maxLat")
+	}
+	var f [4]float64
+	for i, p := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return models.BBox{}, fmt.Errorf("invalid bbox value %q", p)
+		}
+		f[i] = n
+	}
+	b := models.BBox{MinLon: f[0], MinLat: f[1], MaxLon: f[2], MaxLat: f[3]}
+	if b.MinLat > b.MaxLat || b.MinLon > b.MaxLon {
+		return models.BBox{}, errors.New("bbox min must not exceed max")
+	}
+	return b, nil
+}
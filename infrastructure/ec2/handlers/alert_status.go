@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/api"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/auth"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+)
+
+const maxNoteLength = 2000
+
+// UpdateAlertStatus handles PATCH /alerts/:deviceId/:ts.
+func (h *Handler) UpdateAlertStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	deviceID, ts := c.Param("deviceId"), c.Param("ts")
+
+	var req api.StatusUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "invalid json"})
+		return
+	}
+	req.Status = strings.ToUpper(strings.TrimSpace(req.Status))
+	if !models.ValidStatus(req.Status) || req.Status == models.StatusNew {
+		c.JSON(400, gin.H{"error": "status must be one of ACKNOWLEDGED, INVESTIGATING, RESOLVED, FALSE_POSITIVE"})
+		return
+	}
+	if len(req.Note) > maxNoteLength {
+		c.JSON(400, gin.H{"error": "note too long"})
+		return
+	}
+
+	current, err := h.alerts.GeAlertByPK(ctx, deviceID, ts, true)
+	if err != nil {
+		h.logger.Printf("GeAlertByPK error: %v", err)
+		c.JSON(500, gin.H{"error": "internal server error"})
+		return
+	}
+	if current == nil {
+		c.JSON(404, gin.H{"error": "alert not found"})
+		return
+	}
+	if !models.CanTransition(current.Status, req.Status) {
+		c.JSON(409, gin.H{"error": "invalid status transition", "from": current.Status, "to": req.Status})
+		return
+	}
+
+	change := models.StatusChange{
+		From:     current.Status,
+		To:       req.Status,
+		Note:     strings.TrimSpace(req.Note),
+		Operator: operatorName(c),
+		At:       time.Now().UTC().Format(time.RFC3339),
+	}
+	updated, err := h.alerts.UpdateAlertStatus(ctx, deviceID, ts, current.Status, change)
+	if err != nil {
+		if errors.Is(err, repository.ErrStatusConflict) {
+			c.JSON(409, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Printf("UpdateAlertStatus error: %v", err)
+		c.JSON(500, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.logger.Printf("alert %s/%s: %s -> %s by %s", deviceID, ts, change.From, change.To, change.Operator)
+	h.retainAlert(ctx, *updated)
+	h.linkAudio(updated)
+	c.JSON(200, updated)
+}
+
+// operatorName identifies who performed an action: the authenticated
+// principal, or the X-Operator header when authentication is disabled.
+func operatorName(c *gin.Context) string {
+	if p := auth.FromContext(c); p != nil && p.Name != "" {
+		return p.Name
+	}
+	if op := strings.TrimSpace(c.GetHeader("X-Operator")); op != "" {
+		return op
+	}
+	return "anonymous"
+}
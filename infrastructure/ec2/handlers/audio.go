@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+)
+
+// SetPublicBaseURL sets the prefix under which clients reach the API; it is
+// prepended to the audioUrl links.
+func (h *Handler) SetPublicBaseURL(base string) {
+	h.publicBaseURL = strings.TrimSuffix(base, "/")
+}
+
+// StreamAudio handles GET /alerts/:deviceId/:ts/audio. The clip is streamed
+// from S3 through the worker (no presigned URL leaves the API), with Range
+// support so that seeking in a browser <audio> element works. Every request is
+// written to the audit log.
+func (h *Handler) StreamAudio(c *gin.Context) {
+	h.streamMedia(c, "audio")
+}
+
+// StreamMedia handles GET /alerts/:deviceId/:ts/media/:kind: a file derived from
+// the clip (see AudioRepo.ListDerived), served and audited like the clip.
+func (h *Handler) StreamMedia(c *gin.Context) {
+	h.streamMedia(c, c.Param("kind"))
+}
+
+func (h *Handler) streamMedia(c *gin.Context, kind string) {
+	ctx := c.Request.Context()
+	deviceID, ts := c.Param("deviceId"), c.Param("ts")
+	byteRange := audioRange(c.GetHeader("Range"))
+
+	start := time.Now()
+	status, written := 0, int64(0)
+	var streamErr error
+	defer func() {
+		h.logger.Printf("audit: %s deviceId=%s ts=%s operator=%q ip=%s range=%q status=%d bytes=%d took=%s err=%v",
+			kind, deviceID, ts, operatorName(c), c.ClientIP(), byteRange, status, written,
+			time.Since(start).Round(time.Millisecond), streamErr)
+	}()
+
+	fail := func(code int, msg string) {
+		status = code
+		c.JSON(code, gin.H{"error": msg})
+	}
+
+	if !h.audio.Enabled() {
+		fail(404, "audio storage not configured")
+		return
+	}
+	a, err := h.alerts.GeAlertByPK(ctx, deviceID, ts, false)
+	if err != nil {
+		streamErr = err
+		fail(500, "internal server error")
+		return
+	}
+	if a == nil || a.S3Key == "" {
+		fail(404, kind+" not found")
+		return
+	}
+
+	key := a.S3Key
+	if kind != "audio" {
+		// only files listed next to the clip, never an arbitrary key
+		derived, err := h.audio.ListDerived(ctx, a.S3Key)
+		if err != nil {
+			streamErr = err
+			fail(502, "cannot list media")
+			return
+		}
+		key = ""
+		for _, d := range derived {
+			if d.Kind == kind {
+				key = d.Key
+			}
+		}
+		if key == "" {
+			fail(404, "media not found")
+			return
+		}
+	}
+
+	obj, err := h.audio.Open(ctx, key, byteRange)
+	switch {
+	case errors.Is(err, repository.ErrAudioNotFound):
+		fail(404, kind+" not found")
+		return
+	case errors.Is(err, repository.ErrInvalidRange):
+		var rerr *repository.InvalidRangeError
+		if errors.As(err, &rerr) && rerr.Size >= 0 {
+			c.Header("Content-Range", "bytes */"+strconv.FormatInt(rerr.Size, 10))
+		}
+		fail(http.StatusRequestedRangeNotSatisfiable, err.Error())
+		return
+	case err != nil:
+		streamErr = err
+		fail(502, "cannot read "+kind)
+		return
+	}
+	defer obj.Body.Close()
+
+	hdr := c.Writer.Header()
+	hdr.Set("Accept-Ranges", "bytes")
+	hdr.Set("Cache-Control", "private, no-store")
+	hdr.Set("Content-Type", mediaContentType(obj.ContentType, key))
+	hdr.Set("Content-Length", strconv.FormatInt(obj.ContentLength, 10))
+	hdr.Set("Content-Disposition", "inline; filename="+strconv.Quote(path.Base(key)))
+	if obj.ETag != "" {
+		hdr.Set("ETag", obj.ETag)
+	}
+	if !obj.LastModified.IsZero() {
+		hdr.Set("Last-Modified", obj.LastModified.UTC().Format(http.TimeFormat))
+	}
+	status = http.StatusOK
+	if obj.ContentRange != "" {
+		hdr.Set("Content-Range", obj.ContentRange)
+		status = http.StatusPartialContent
+	}
+	c.Status(status)
+
+	written, streamErr = io.Copy(c.Writer, obj.Body)
+}
+
+// audioRange passes through a single "bytes=" range. Multi-range and other
+// units are not supported by S3, so they are ignored and the full clip is
+// served, which RFC 9110 allows.
+func audioRange(v string) string {
+	v = strings.TrimSpace(v)
+	if !strings.HasPrefix(v, "bytes=") || strings.Contains(v, ",") {
+		return ""
+	}
+	return v
+}
+
+func mediaContentType(ct, key string) string {
+	if ct != "" && ct != "binary/octet-stream" && ct != "application/octet-stream" {
+		return ct
+	}
+	switch strings.ToLower(path.Ext(key)) {
+	case ".wav":
+		return "audio/wav"
+	case ".mp3":
+		return "audio/mpeg"
+	case ".ogg", ".opus":
+		return "audio/ogg"
+	case ".flac":
+		return "audio/flac"
+	case ".png":
+		return "image/png"
+	case ".json":
+		return "application/json"
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/export"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+)
+
+// SetZones registers the named areas accepted by ?zone= on the exports.
+func (h *Handler) SetZones(zones map[string]models.BBox) {
+	h.zones = zones
+}
+
+// ExportSourcesKML serves GET /sources.kml for Google Earth.
+func (h *Handler) ExportSourcesKML(c *gin.Context) {
+	srcs, name, ok := h.exportSources(c)
+	if !ok {
+		return
+	}
+	c.Header("Content-Type", export.KMLContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".kml"))
+	if err := export.WriteSourcesKML(c.Writer, name, srcs); err != nil {
+		h.logger.Printf("kml export error: %v", err)
+	}
+}
+
+// ExportSourcesGPX serves GET /sources.gpx for handheld GPS units.
+func (h *Handler) ExportSourcesGPX(c *gin.Context) {
+	srcs, name, ok := h.exportSources(c)
+	if !ok {
+		return
+	}
+	c.Header("Content-Type", export.GPXContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".gpx"))
+	if err := export.WriteSourcesGPX(c.Writer, name, srcs, time.Now().UTC()); err != nil {
+		h.logger.Printf("gpx export error: %v", err)
+	}
+}
+
+// exportSources collects the sources for an export according to ?state=
+// (active by default, expired or all), ?from/?to and ?zone or ?bbox.
+func (h *Handler) exportSources(c *gin.Context) ([]processor.SourceGroup, string, bool) {
+	ctx := c.Request.Context()
+
+	from, err := parseTime(c.Query("from"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid from"})
+		return nil, "", false
+	}
+	to, err := parseTime(c.Query("to"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid to"})
+		return nil, "", false
+	}
+
+	name := "sources"
+	var area *models.BBox
+	if zone := c.Query("zone"); zone != "" {
+		bb, ok := h.zones[zone]
+		if !ok {
+			c.JSON(400, gin.H{"error": "unknown zone"})
+			return nil, "", false
+		}
+		area = &bb
+		name += "-" + zone
+	} else if v := c.Query("bbox"); v != "" {
+		bb, err := parseBBox(v)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return nil, "", false
+		}
+		area = &bb
+	}
+
+	keep := func(sg processor.SourceGroup) bool {
+		if area != nil && !area.Contains(sg.Lat, sg.Lon) {
+			return false
+		}
+		if !from.IsZero() && sg.UpdatedAt.Before(from) {
+			return false
+		}
+		if !to.IsZero() && sg.UpdatedAt.After(to) {
+			return false
+		}
+		return true
+	}
+
+	var states []string
+	switch c.DefaultQuery("state", "active") {
+	case "active":
+		return h.sources.List(keep), name, true
+	case "expired":
+		states = []string{models.SourceExpired}
+	case "all":
+		states = []string{models.SourceActive, models.SourceExpired}
+	default:
+		c.JSON(400, gin.H{"error": "state must be active, expired or all"})
+		return nil, "", false
+	}
+
+	// historical sources come from the store, default window is the last 24h
+	if to.IsZero() {
+		to = time.Now().UTC()
+	}
+	if from.IsZero() {
+		from = to.Add(-24 * time.Hour)
+	}
+	res := []processor.SourceGroup{}
+	for _, st := range states {
+		recs, err := h.store.ListSources(ctx, st, from, to)
+		if err != nil {
+			h.logger.Printf("ListSources error: %v", err)
+			c.JSON(500, gin.H{"error": "internal server error"})
+			return nil, "", false
+		}
+		for _, rec := range recs {
+			if area != nil && !area.Contains(rec.Lat, rec.Lon) {
+				continue
+			}
+			sg, err := h.loadSource(ctx, rec)
+			if err != nil {
+				h.logger.Printf("loadSource %s error: %v", rec.ID, err)
+				c.JSON(500, gin.H{"error": "internal server error"})
+				return nil, "", false
+			}
+			res = append(res, sg)
+		}
+	}
+	return res, name, true
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/export"
+)
+
+// wantsGeoJSON reports whether the client asked for GeoJSON, either through
+// the Accept header or the ".geojson" route suffix.
+func wantsGeoJSON(c *gin.Context) bool {
+	if strings.HasSuffix(c.Request.URL.Path, ".geojson") {
+		return true
+	}
+	return strings.Contains(c.GetHeader("Accept"), export.GeoJSONContentType)
+}
+
+func writeGeoJSON(c *gin.Context, fc export.FeatureCollection) {
+	c.Header("Vary", "Accept")
+	c.Render(200, geoJSONRender{fc})
+}
+
+type geoJSONRender struct {
+	fc export.FeatureCollection
+}
+
+func (r geoJSONRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.fc)
+}
+
+func (r geoJSONRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", export.GeoJSONContentType)
+}
@@ -2,43 +2,68 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"sync"
+	"net/url"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-
 	"github.com/gin-gonic/gin"
-	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/config"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/api"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/export"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/metrics"
 	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
 	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
 	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/stream"
 )
 
 type Handler struct {
-	repo   *repository.Repo
-	logger *log.Logger
-	mem    *processor.Memory
-}
+	alerts  repository.AlertStore
+	devices repository.SensorStore
+	audio   *repository.AudioRepo
+	// nil when telemetry_table is not configured
+	telemetry *repository.TelemetryRepo
+	store     repository.SourceStore
+	logger    *log.Logger
+	mem       *processor.Memory
+	sources   *processor.SourceSet
+	// sensor positions for alerts sent without lat/lon, may be nil
+	positions processor.Positions
+	events    *stream.Broker
+	zones     map[string]models.BBox
+	// nil = TTLs are not managed by the worker
+	retention *models.Retention
 
-var (
-	allSources []processor.SourceGroup
-	allMu      sync.Mutex
-)
+	sensorStaleAfter   time.Duration
+	sensorOfflineAfter time.Duration
+
+	publicBaseURL string
+}
 
-func NewHandler(repo *repository.Repo, mem *processor.Memory, logger *log.Logger) *Handler {
+func NewHandler(alerts repository.AlertStore, devices repository.SensorStore, audio *repository.AudioRepo, store repository.SourceStore, mem *processor.Memory, sources *processor.SourceSet, events *stream.Broker, logger *log.Logger) *Handler {
 	return &Handler{
-		repo:   repo,
-		mem:    mem,
-		logger: logger,
+		alerts:  alerts,
+		devices: devices,
+		audio:   audio,
+		store:   store,
+		mem:     mem,
+		sources: sources,
+		events:  events,
+		logger:  logger,
+
+		sensorStaleAfter:   5 * time.Minute,
+		sensorOfflineAfter: 15 * time.Minute,
 	}
 }
 
 func (h *Handler) HandleEnvelope(ctx context.Context, env models.Envelope) error {
-	it, err := h.repo.GeAlertByPK(ctx, env.DeviceID, env.TS, true)
+	defer metrics.Since(metrics.HandleDuration, time.Now())
+	if ts, err := time.Parse(time.RFC3339, env.TS); err == nil {
+		metrics.AlertDelay.Observe(time.Since(ts).Seconds())
+	}
+
+	it, err := h.alerts.GeAlertByPK(ctx, env.DeviceID, env.TS, true)
 	if err != nil {
 		return err
 	}
@@ -48,8 +73,10 @@ func (h *Handler) HandleEnvelope(ctx context.Context, env models.Envelope) error
 	fmt.Printf("ts       : %s\n", env.TS)
 
 	if it != nil {
+		processor.Locate(it, h.positions)
 		// memory add
 		h.mem.Add(it)
+		h.publishAlert(it)
 
 		fmt.Printf("s3Key    : %s\n", it.S3Key)
 		fmt.Printf("lat,lon  : %.6f, %.6f\n", it.Lat, it.Lon)
@@ -66,62 +93,31 @@ func (h *Handler) HandleEnvelope(ctx context.Context, env models.Envelope) error
 	active := h.mem.GetAll()
 	sources := processor.FindPotentialSources(active, 3)
 
-	allMu.Lock()
-	allSources = append(allSources, sources...)
-	total := len(allSources)
-	allMu.Unlock()
+	created, updated := h.sources.Upsert(sources, time.Now().UTC())
+	h.saveSources(ctx, created)
+	h.saveSources(ctx, updated)
+	for _, sg := range created {
+		h.publishSource(stream.EventSourceCreated, sg)
+	}
+	for _, sg := range updated {
+		h.publishSource(stream.EventSourceUpdated, sg)
+	}
 
 	// callujesz triangualcje dla active
 	fmt.Printf("active alerts: %d\n", len(active))
-	fmt.Printf("new potential sources: %d, total stored sources: %d\n", len(sources), total)
+	fmt.Printf("potential sources: %d (new %d, updated %d), total stored sources: %d\n",
+		len(sources), len(created), len(updated), h.sources.Len())
 
 	return nil
 }
 
-func (h *Handler) ListSensors(c *gin.Context) {
-	ctx := c.Request.Context()
-	sensors, err := h.repo.GetAllSensors(ctx)
-	if err != nil {
-		h.logger.Printf("GetAllSensors error: %v", err)
-		c.JSON(500, gin.H{"error": "internal server error"})
-		return
-	}
-
-	c.JSON(200, sensors)
-}
-
 func (h *Handler) ListSources(c *gin.Context) {
-	allMu.Lock()
-	srcs := make([]processor.SourceGroup, len(allSources))
-	for i := range allSources {
-		sg := allSources[i]
-		srcs[i].Lat = sg.Lat
-		srcs[i].Lon = sg.Lon
-
-		if len(sg.Alerts) > 0 {
-			srcs[i].Alerts = make([]*models.Alert, len(sg.Alerts))
-			for j := range sg.Alerts {
-				if sg.Alerts[j] == nil {
-					srcs[i].Alerts[j] = nil
-					continue
-				}
-				copyAlert := *sg.Alerts[j]
-				srcs[i].Alerts[j] = &copyAlert
-			}
-		}
+	if state := c.Query("state"); state != "" {
+		h.listStoredSources(c, state)
+		return
 	}
-	allMu.Unlock()
 
-	bucket := config.AppConfig.AWS.BucketName
-	var presigner *s3.PresignClient
-	if bucket != "" {
-		awsCfg, err := awsConfig.LoadDefaultConfig(c.Request.Context(), awsConfig.WithRegion(config.AppConfig.AWS.Region))
-		if err != nil {
-			h.logger.Printf("warning: cannot load aws config for presign: %v", err)
-		} else {
-			presigner = s3.NewPresignClient(s3.NewFromConfig(awsCfg))
-		}
-	}
+	srcs := h.sources.List(nil)
 
 	for i := range srcs {
 		sg := &srcs[i]
@@ -129,100 +125,154 @@ func (h *Handler) ListSources(c *gin.Context) {
 			if sg.Alerts[j] == nil {
 				continue
 			}
-			a := sg.Alerts[j]
-			if a.S3Key == "" || bucket == "" || presigner == nil {
-				continue
-			}
-			in := &s3.GetObjectInput{
-				Bucket: aws.String(bucket),
-				Key:    aws.String(a.S3Key),
-			}
-			ps, err := presigner.PresignGetObject(c.Request.Context(), in, func(opts *s3.PresignOptions) {
-				opts.Expires = 15 * time.Minute
-			})
-			if err != nil {
-				h.logger.Printf("presign error for key %s: %v", a.S3Key, err)
-				continue
-			}
-			a.S3Key = ps.URL
+			h.linkAudio(sg.Alerts[j])
 		}
 	}
 
-	c.JSON(200, gin.H{
-		"count":   len(srcs),
-		"sources": srcs,
-	})
+	if wantsGeoJSON(c) {
+		writeGeoJSON(c, export.SourcesGeoJSON(srcs))
+		return
+	}
+	c.JSON(200, api.SourceList{Count: len(srcs), Sources: srcs})
 }
 
 func (h *Handler) ListAlerts(c *gin.Context) {
 	ctx := c.Request.Context()
-	alerts, err := h.repo.GetAlertsLastHour(ctx)
+	q, err := parseAlertQuery(c)
 	if err != nil {
-		h.logger.Printf("GetAlertsLastHour error: %v", err)
-		c.JSON(500, gin.H{"error": "internal server error"})
+		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
-	bucket := config.AppConfig.AWS.BucketName
-	var presigner *s3.PresignClient
-	if bucket != "" {
-		awsCfg, err := awsConfig.LoadDefaultConfig(ctx, awsConfig.WithRegion(config.AppConfig.AWS.Region))
-		if err != nil {
-			h.logger.Printf("warning: cannot load aws config for presign: %v", err)
-		} else {
-			presigner = s3.NewPresignClient(s3.NewFromConfig(awsCfg))
+	page, err := h.alerts.QueryAlerts(ctx, q)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
 		}
+		h.logger.Printf("QueryAlerts error: %v", err)
+		c.JSON(500, gin.H{"error": "internal server error"})
+		return
 	}
 
-	respAlerts := make([]models.Alert, 0, len(alerts))
-	for _, a := range alerts {
+	respAlerts := make([]models.Alert, 0, len(page.Alerts))
+	for _, a := range page.Alerts {
 		ra := a
-		if ra.S3Key != "" && bucket != "" && presigner != nil {
-			in := &s3.GetObjectInput{
-				Bucket: aws.String(bucket),
-				Key:    aws.String(ra.S3Key),
-			}
-			ps, err := presigner.PresignGetObject(ctx, in, func(opts *s3.PresignOptions) {
-				opts.Expires = 15 * time.Minute
-			})
-			if err != nil {
-				h.logger.Printf("presign error for key %s: %v", ra.S3Key, err)
-			} else {
-				ra.S3Key = ps.URL
-			}
-		}
+		h.linkAudio(&ra)
 		respAlerts = append(respAlerts, ra)
 	}
 
-	c.JSON(200, gin.H{
-		"count":  len(respAlerts),
-		"alerts": respAlerts,
-	})
+	if wantsGeoJSON(c) {
+		fc := export.AlertsGeoJSON(respAlerts)
+		fc.NextCursor = page.NextCursor
+		writeGeoJSON(c, fc)
+		return
+	}
+
+	c.JSON(200, api.AlertList{Count: len(respAlerts), Alerts: respAlerts, NextCursor: page.NextCursor})
+}
+
+// linkAudio points a (copied) alert at the audio proxy instead of S3, so clips
+// are only served through GET /alerts/:deviceId/:ts/audio.
+func (h *Handler) linkAudio(a *models.Alert) {
+	if a.S3Key == "" || !h.audio.Enabled() {
+		return
+	}
+	a.AudioURL = h.publicBaseURL + "/alerts/" + url.PathEscape(a.DeviceID) + "/" + url.PathEscape(a.TS) + "/audio"
+}
+
+// RunSourceCleaner drops sources older than maxAge every interval until ctx is cancelled.
+func (h *Handler) RunSourceCleaner(ctx context.Context, interval, maxAge time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.CleanOldSources(maxAge)
+		}
+	}
 }
 
 func (h *Handler) CleanOldSources(maxAge time.Duration) {
-	allMu.Lock()
-	defer allMu.Unlock()
-
-	cutoff := time.Now().Add(-maxAge)
-	filtered := []processor.SourceGroup{}
-
-	for _, sg := range allSources {
-		hasRecent := false
-		for _, alert := range sg.Alerts {
-			if alert != nil {
-				ts, _ := time.Parse(time.RFC3339, alert.CreatedAt)
-				if ts.After(cutoff) {
-					hasRecent = true
-					break
-				}
-			}
+	now := time.Now()
+	before := h.sources.Len()
+	expired := h.sources.Expire(now.Add(-maxAge))
+	h.logger.Printf("Cleaned old sources: %d -> %d", before, before-len(expired))
+
+	ctx := context.Background()
+	for _, sg := range expired {
+		// the source stays queryable via GET /sources?state=expired
+		if err := h.store.ExpireSource(ctx, sg.ID, now); err != nil {
+			h.logger.Printf("ExpireSource %s error: %v", sg.ID, err)
 		}
-		if hasRecent {
-			filtered = append(filtered, sg)
+		h.publishSource(stream.EventSourceExpired, sg)
+	}
+}
+
+// publishAlert pushes a copy of a new alert (with its audio proxy link) to stream clients.
+func (h *Handler) publishAlert(a *models.Alert) {
+	if h.events == nil {
+		return
+	}
+	cp := *a
+	h.linkAudio(&cp)
+	h.events.Publish(stream.EventAlertCreated, cp)
+}
+
+func (h *Handler) publishSource(typ string, sg processor.SourceGroup) {
+	if h.events == nil {
+		return
+	}
+	cp := sg
+	cp.Alerts = make([]*models.Alert, 0, len(sg.Alerts))
+	for _, a := range sg.Alerts {
+		if a == nil {
+			continue
 		}
+		ac := *a
+		h.linkAudio(&ac)
+		cp.Alerts = append(cp.Alerts, &ac)
+	}
+	h.events.Publish(typ, cp)
+}
+
+// GetSource handles GET /sources/:id.
+func (h *Handler) GetSource(c *gin.Context) {
+	sg, ok := h.findSource(c)
+	if !ok {
+		return
+	}
+	for _, a := range sg.Alerts {
+		h.linkAudio(a)
 	}
+	c.JSON(200, sg)
+}
 
-	h.logger.Printf("Cleaned old sources: %d -> %d", len(allSources), len(filtered))
-	allSources = filtered
+// findSource looks up :id in the live set and falls back to the store. It
+// writes the error response itself.
+func (h *Handler) findSource(c *gin.Context) (processor.SourceGroup, bool) {
+	ctx := c.Request.Context()
+	sg, ok := h.sources.Get(c.Param("id"))
+	if ok {
+		return sg, true
+	}
+	// not live any more, try the store
+	rec, err := h.store.GetSource(ctx, c.Param("id"))
+	if err != nil {
+		h.logger.Printf("GetSource error: %v", err)
+		c.JSON(500, gin.H{"error": "internal server error"})
+		return sg, false
+	}
+	if rec == nil {
+		c.JSON(404, gin.H{"error": "source not found"})
+		return sg, false
+	}
+	if sg, err = h.loadSource(ctx, *rec); err != nil {
+		h.logger.Printf("loadSource error: %v", err)
+		c.JSON(500, gin.H{"error": "internal server error"})
+		return sg, false
+	}
+	return sg, true
 }
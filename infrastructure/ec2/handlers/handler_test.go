@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/stream"
+)
+
+func newTestHandler(t *testing.T) (*Handler, *repository.MemoryStore, *stream.Broker) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	st := repository.NewMemoryStore()
+	broker := stream.NewBroker(100, 16)
+	t.Cleanup(broker.Close)
+	h := NewHandler(st, st, nil, repository.NewMemorySourceStore(), processor.NewMemory(time.Minute),
+		processor.NewSourceSet(processor.DefaultMinSharedAlerts), broker, log.New(io.Discard, "", 0))
+	return h, st, broker
+}
+
+func serve(h gin.HandlerFunc, method, path, pattern, body string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.Handle(method, pattern, h)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestHandleEnvelopeLocalizesSource(t *testing.T) {
+	h, st, _ := newTestHandler(t)
+	ts := time.Now().UTC().Format(time.RFC3339)
+	for _, a := range []models.Alert{
+		{DeviceID: "A", TS: ts, Lat: 50.000, Lon: 20.000, Distance: 400},
+		{DeviceID: "B", TS: ts, Lat: 50.004, Lon: 20.000, Distance: 400},
+		{DeviceID: "C", TS: ts, Lat: 50.002, Lon: 20.005, Distance: 400},
+	} {
+		st.PutAlert(a)
+		if err := h.HandleEnvelope(context.Background(), models.Envelope{DeviceID: a.DeviceID, TS: a.TS}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srcs := h.sources.List(nil)
+	if len(srcs) != 1 || len(srcs[0].Alerts) != 3 {
+		t.Fatalf("sources = %+v", srcs)
+	}
+	stored, _ := h.store.GetSource(context.Background(), srcs[0].ID)
+	if stored == nil || stored.State != models.SourceActive {
+		t.Fatalf("stored source = %+v", stored)
+	}
+}
+
+func TestHandleEnvelopeUsesRegisteredPosition(t *testing.T) {
+	h, st, _ := newTestHandler(t)
+	st.PutSensor(models.Sensor{DeviceID: "A", Lat: 50.06, Lon: 19.94})
+	reg := repository.NewSensorRegistry(st, h.logger)
+	if err := reg.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	h.SetPositions(reg)
+
+	ts := time.Now().UTC().Format(time.RFC3339)
+	st.PutAlert(models.Alert{DeviceID: "A", TS: ts, Distance: 300})
+	if err := h.HandleEnvelope(context.Background(), models.Envelope{DeviceID: "A", TS: ts}); err != nil {
+		t.Fatal(err)
+	}
+	active := h.mem.GetAll()
+	if len(active) != 1 || active[0].Lat != 50.06 || active[0].Lon != 19.94 {
+		t.Fatalf("active = %+v", active)
+	}
+}
+
+func TestRestoreSourcesExpiresStale(t *testing.T) {
+	h, _, _ := newTestHandler(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	for id, updated := range map[string]time.Time{"fresh": now.Add(-time.Hour), "stale": now.Add(-10 * 24 * time.Hour)} {
+		ts := updated.Format(time.RFC3339)
+		h.store.SaveSource(ctx, models.Source{ID: id, State: models.SourceActive, FirstSeen: ts, UpdatedAt: ts})
+	}
+	if err := h.RestoreSources(ctx, 7*24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if srcs := h.sources.List(nil); len(srcs) != 1 || srcs[0].ID != "fresh" {
+		t.Fatalf("restored = %+v", srcs)
+	}
+	if rec, _ := h.store.GetSource(ctx, "stale"); rec == nil || rec.State != models.SourceExpired {
+		t.Fatalf("stale source = %+v", rec)
+	}
+}
+
+func TestUpdateAlertStatus(t *testing.T) {
+	h, st, _ := newTestHandler(t)
+	st.PutAlert(models.Alert{DeviceID: "A", TS: "2026-10-19T10:00:00Z", Status: models.StatusNew})
+
+	w := serve(h.UpdateAlertStatus, "PATCH", "/alerts/A/2026-10-19T10:00:00Z", "/alerts/:deviceId/:ts", `{"status":"acknowledged","note":"jadę"}`)
+	if w.Code != 200 {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var got models.Alert
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if got.Status != models.StatusAcknowledged || len(got.History) != 1 || got.History[0].Note != "jadę" {
+		t.Fatalf("updated = %+v", got)
+	}
+
+	// ACKNOWLEDGED -> ACKNOWLEDGED is not a transition
+	w = serve(h.UpdateAlertStatus, "PATCH", "/alerts/A/2026-10-19T10:00:00Z", "/alerts/:deviceId/:ts", `{"status":"acknowledged"}`)
+	if w.Code != 409 {
+		t.Fatalf("repeated update: status %d", w.Code)
+	}
+	w = serve(h.UpdateAlertStatus, "PATCH", "/alerts/X/2026-10-19T10:00:00Z", "/alerts/:deviceId/:ts", `{"status":"resolved"}`)
+	if w.Code != 404 {
+		t.Fatalf("missing alert: status %d", w.Code)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	h, st, _ := newTestHandler(t)
+	r := models.DefaultRetention
+	h.SetRetention(r)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	ts := now.Format(time.RFC3339)
+	ttl := func(d time.Duration) int64 { return now.Add(d).Unix() }
+
+	lone := models.Alert{DeviceID: "D", TS: "2026-10-19T10:00:00Z", Status: models.StatusNew}
+	lone.TTL = r.TTL(lone, false, false) // as lambda-alert writes it
+	st.PutAlert(lone)
+	for _, a := range []models.Alert{
+		{DeviceID: "A", TS: ts, Lat: 50.000, Lon: 20.000, Distance: 400},
+		{DeviceID: "B", TS: ts, Lat: 50.004, Lon: 20.000, Distance: 400},
+		{DeviceID: "C", TS: ts, Lat: 50.002, Lon: 20.005, Distance: 400},
+	} {
+		a.TTL = ttl(r.Unattached)
+		st.PutAlert(a)
+		if err := h.HandleEnvelope(ctx, models.Envelope{DeviceID: a.DeviceID, TS: a.TS}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srcs := h.sources.List(nil)
+	if len(srcs) != 1 {
+		t.Fatalf("sources = %+v", srcs)
+	}
+	for _, dev := range []string{"A", "B", "C"} {
+		a, _ := st.GeAlertByPK(ctx, dev, ts, true)
+		if a.SourceID != srcs[0].ID || a.TTL != ttl(r.Attached) {
+			t.Fatalf("attached alert %s: sourceId=%q ttl=%d", dev, a.SourceID, a.TTL)
+		}
+	}
+
+	// confirming one alert exempts the whole source
+	path := "/alerts/B/" + ts
+	if w := serve(h.UpdateAlertStatus, "PATCH", path, "/alerts/:deviceId/:ts", `{"status":"investigating"}`); w.Code != 200 {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	for _, dev := range []string{"A", "B", "C"} {
+		if a, _ := st.GeAlertByPK(ctx, dev, ts, true); a.TTL != 0 {
+			t.Fatalf("alert %s of confirmed incident: ttl=%d", dev, a.TTL)
+		}
+	}
+
+	if w := serve(h.UpdateAlertStatus, "PATCH", "/alerts/D/2026-10-19T10:00:00Z", "/alerts/:deviceId/:ts", `{"status":"false_positive"}`); w.Code != 200 {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	got, _ := st.GeAlertByPK(ctx, "D", "2026-10-19T10:00:00Z", true)
+	if want := r.TTL(*got, false, false); got.TTL != want || want >= lone.TTL {
+		t.Fatalf("false positive ttl = %d, want %d (< %d)", got.TTL, want, lone.TTL)
+	}
+}
+
+func TestRetentionDisabledKeepsIncident(t *testing.T) {
+	h, st, _ := newTestHandler(t)
+	ctx := context.Background()
+	ts := time.Now().UTC().Format(time.RFC3339)
+	for _, a := range []models.Alert{
+		{DeviceID: "A", TS: ts, Lat: 50.000, Lon: 20.000, Distance: 400, TTL: 1},
+		{DeviceID: "B", TS: ts, Lat: 50.004, Lon: 20.000, Distance: 400, TTL: 1},
+		{DeviceID: "C", TS: ts, Lat: 50.002, Lon: 20.005, Distance: 400, TTL: 1},
+	} {
+		st.PutAlert(a)
+		if err := h.HandleEnvelope(ctx, models.Envelope{DeviceID: a.DeviceID, TS: a.TS}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a, _ := st.GeAlertByPK(ctx, "A", ts, true); a.TTL != 1 || a.SourceID == "" {
+		t.Fatalf("attached alert: sourceId=%q ttl=%d", a.SourceID, a.TTL)
+	}
+	if w := serve(h.UpdateAlertStatus, "PATCH", "/alerts/A/"+ts, "/alerts/:deviceId/:ts", `{"status":"resolved"}`); w.Code != 200 {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	for _, dev := range []string{"A", "B", "C"} {
+		if a, _ := st.GeAlertByPK(ctx, dev, ts, true); a.TTL != 0 {
+			t.Fatalf("alert %s of confirmed incident: ttl=%d", dev, a.TTL)
+		}
+	}
+}
+
+func TestListAlertsRejectsLongRange(t *testing.T) {
+	h, _, _ := newTestHandler(t)
+	if w := serve(h.ListAlerts, "GET", "/alerts?from=0001-01-02T00:00:00Z", "/alerts", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("unbounded range: status %d", w.Code)
+	}
+	from := time.Now().UTC().Add(-24 * time.Hour).Format(time.RFC3339)
+	if w := serve(h.ListAlerts, "GET", "/alerts?from="+from, "/alerts", ""); w.Code != 200 {
+		t.Fatalf("one day: status %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestListSensorsStatus(t *testing.T) {
+	h, st, _ := newTestHandler(t)
+	now := time.Now().UTC()
+	st.PutSensor(models.Sensor{DeviceID: "on", LastSeen: now.Add(-time.Hour), LastHeartbeat: now.Add(-time.Minute)})
+	st.PutSensor(models.Sensor{DeviceID: "stale", LastSeen: now.Add(-10 * time.Minute)})
+	st.PutSensor(models.Sensor{DeviceID: "off", LastSeen: now.Add(-2 * time.Hour)})
+
+	w := serve(h.ListSensors, "GET", "/sensors?status=stale", "/sensors", "")
+	var got []models.Sensor
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if w.Code != 200 || len(got) != 1 || got[0].DeviceID != "stale" || got[0].Status != models.SensorStale {
+		t.Fatalf("status %d, sensors %+v", w.Code, got)
+	}
+
+	if w := serve(h.ListSensors, "GET", "/sensors?status=dead", "/sensors", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid status: %d", w.Code)
+	}
+}
+
+func TestCheckSensorsPublishesTransitions(t *testing.T) {
+	h, st, broker := newTestHandler(t)
+	sub, _, _ := broker.Subscribe("", nil)
+	defer broker.Unsubscribe(sub)
+
+	now := time.Now().UTC()
+	st.PutSensor(models.Sensor{DeviceID: "A", LastHeartbeat: now})
+
+	state, err := h.checkSensors(context.Background(), nil, now)
+	if err != nil || state["A"] != models.SensorOnline {
+		t.Fatalf("first check: %v %v", state, err)
+	}
+
+	state, _ = h.checkSensors(context.Background(), state, now.Add(20*time.Minute))
+	ev := <-sub.Events()
+	if ev.Type != stream.EventSensorOffline || state["A"] != models.SensorOffline {
+		t.Fatalf("event %s, state %v", ev.Type, state)
+	}
+
+	st.PutSensor(models.Sensor{DeviceID: "A", LastHeartbeat: now.Add(20 * time.Minute)})
+	h.checkSensors(context.Background(), state, now.Add(20*time.Minute))
+	if ev := <-sub.Events(); ev.Type != stream.EventSensorOnline {
+		t.Fatalf("event %s", ev.Type)
+	}
+}
+
+func TestReadinessProbeIsStatusOnlyAndCached(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHealthHandler(nil, nil, nil)
+	calls := 0
+	h.AddCheck("dynamodb", func(ctx context.Context) error {
+		calls++
+		return errors.New("secret table name")
+	})
+
+	for range 3 {
+		w := serve(h.Readiness, "GET", "/readyz", "/readyz", "")
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("status %d", w.Code)
+		}
+		if got := strings.TrimSpace(w.Body.String()); got != `{"status":"not_ready"}` {
+			t.Fatalf("body %s", got)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("check ran %d times, want 1 within the cache TTL", calls)
+	}
+
+	w := serve(h.Health, "GET", "/health", "/health", "")
+	if w.Code != 200 || !strings.Contains(w.Body.String(), "secret table name") || calls != 1 {
+		t.Fatalf("health: status %d, calls %d: %s", w.Code, calls, w.Body)
+	}
+}
+
+func TestStreamAudioRange(t *testing.T) {
+	const clip = "0123456789"
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/clips/A/clip.wav" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Length", strconv.Itoa(len(clip)))
+			return
+		}
+		switch r.Header.Get("Range") {
+		case "bytes=2-5":
+			w.Header().Set("Content-Range", "bytes 2-5/10")
+			w.WriteHeader(http.StatusPartialContent)
+			io.WriteString(w, clip[2:6])
+		case "bytes=20-":
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			io.WriteString(w, `<Error><Code>InvalidRange</Code><Message>The requested range is not satisfiable</Message></Error>`)
+		default:
+			io.WriteString(w, clip)
+		}
+	}))
+	defer fake.Close()
+	s3Cli := s3.New(s3.Options{
+		Region:       "eu-north-1",
+		BaseEndpoint: aws.String(fake.URL),
+		UsePathStyle: true,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "local", SecretAccessKey: "local"}, nil
+		}),
+	})
+
+	h, st, _ := newTestHandler(t)
+	h.audio = repository.NewAudioRepo(s3Cli, nil, "clips")
+	st.PutAlert(models.Alert{DeviceID: "A", TS: "t1", S3Key: "A/clip.wav"})
+
+	get := func(rng string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.GET("/alerts/:deviceId/:ts/audio", h.StreamAudio)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/alerts/A/t1/audio", nil)
+		req.Header.Set("Range", rng)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("bytes=2-5")
+	if w.Code != http.StatusPartialContent || w.Body.String() != "2345" || w.Header().Get("Content-Range") != "bytes 2-5/10" {
+		t.Fatalf("partial: status %d, Content-Range %q, body %q", w.Code, w.Header().Get("Content-Range"), w.Body)
+	}
+	w = get("bytes=20-")
+	if w.Code != http.StatusRequestedRangeNotSatisfiable || w.Header().Get("Content-Range") != "bytes */10" {
+		t.Fatalf("unsatisfiable: status %d, Content-Range %q", w.Code, w.Header().Get("Content-Range"))
+	}
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/api"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/edge"
+	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/queue"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/resilience"
+)
+
+const (
+	checkTimeout = 3 * time.Second
+	// the loop waits at most 20s on ReceiveMessage plus 1m of backoff
+	loopStallAfter = 3 * time.Minute
+	// messages waiting but nothing processed for this long = stuck consumer
+	processStallAfter = 5 * time.Minute
+	// /readyz is public; its dependency checks run at most this often
+	readyCacheTTL = 10 * time.Second
+)
+
+type healthCheck struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// QueueWorker is the message loop watched by the probes: the SQS consumer or
+// the local queue of the edge gateway. Name is the readiness check name.
+type QueueWorker interface {
+	Name() string
+	Activity() queue.Activity
+	Backlog(ctx context.Context) (queue.Backlog, error)
+}
+
+type HealthHandler struct {
+	breakers  []*resilience.Breaker
+	consumer  QueueWorker
+	dlq       *queue.DLQ
+	sync      func() edge.SyncStatus
+	mem       *processor.Memory
+	sources   *processor.SourceSet
+	checks    []healthCheck
+	startedAt time.Time
+
+	readyMu sync.Mutex
+	ready   api.Readiness
+	readyAt time.Time
+}
+
+func NewHealthHandler(consumer QueueWorker, mem *processor.Memory, sources *processor.SourceSet, breakers ...*resilience.Breaker) *HealthHandler {
+	return &HealthHandler{
+		breakers:  breakers,
+		consumer:  consumer,
+		mem:       mem,
+		sources:   sources,
+		startedAt: time.Now().UTC(),
+	}
+}
+
+// AddCheck registers a dependency probe for /readyz (e.g. DynamoDB, S3).
+func (h *HealthHandler) AddCheck(name string, fn func(ctx context.Context) error) {
+	h.checks = append(h.checks, healthCheck{name: name, fn: fn})
+}
+
+// SetDLQ makes /readyz report the dead-letter backlog (informational only).
+func (h *HealthHandler) SetDLQ(dlq *queue.DLQ) {
+	h.dlq = dlq
+}
+
+// SetSync makes /readyz report the upload state of an edge gateway.
+func (h *HealthHandler) SetSync(status func() edge.SyncStatus) {
+	h.sync = status
+}
+
+// Health handles GET /health (admin): circuit breaker state of every AWS
+// dependency plus the liveness and readiness details. The status is "degraded"
+// while any breaker is not closed or a probe fails.
+func (h *HealthHandler) Health(c *gin.Context) {
+	resp := api.Health{
+		Status:    "ok",
+		Breakers:  make([]resilience.Status, 0, len(h.breakers)),
+		Liveness:  h.liveness(),
+		Readiness: h.readiness(c.Request.Context()),
+	}
+	for _, b := range h.breakers {
+		st := b.Status()
+		if st.State != resilience.StateClosed {
+			resp.Status = "degraded"
+		}
+		resp.Breakers = append(resp.Breakers, st)
+	}
+	if resp.Liveness.Status != "ok" || resp.Readiness.Status != "ready" {
+		resp.Status = "degraded"
+	}
+	c.JSON(200, resp)
+}
+
+// Liveness handles GET /healthz. It only fails when the consumer loop has
+// stopped iterating (deadlock, stuck call), which a restart would fix.
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	l := h.liveness()
+	code := 200
+	if l.Status != "ok" {
+		code = 503
+	}
+	c.JSON(code, api.Probe{Status: l.Status})
+}
+
+// Readiness handles GET /readyz. Any failed check or a consumer that is not
+// running or not making progress makes it 503.
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	r := h.readiness(c.Request.Context())
+	code := 200
+	if r.Status != "ready" {
+		code = 503
+	}
+	c.JSON(code, api.Probe{Status: r.Status})
+}
+
+func (h *HealthHandler) liveness() api.Liveness {
+	resp := api.Liveness{Status: "ok", StartedAt: h.startedAt}
+	if h.consumer != nil {
+		act := h.consumer.Activity()
+		if !act.LastLoopAt.IsZero() {
+			age := time.Since(act.LastLoopAt)
+			resp.LoopAge = age.Round(time.Second).String()
+			if act.Running && age > loopStallAfter {
+				resp.Status = "stalled"
+			}
+		}
+	}
+	return resp
+}
+
+// readiness returns the last result of checkReadiness, running it again once
+// it is older than readyCacheTTL. Concurrent callers wait for a single run.
+func (h *HealthHandler) readiness(ctx context.Context) api.Readiness {
+	h.readyMu.Lock()
+	defer h.readyMu.Unlock()
+	if h.readyAt.IsZero() || time.Since(h.readyAt) > readyCacheTTL {
+		// the result is shared, so a caller going away must not fail the checks
+		h.ready = h.checkReadiness(context.WithoutCancel(ctx))
+		h.readyAt = time.Now()
+	}
+	return h.ready
+}
+
+// checkReadiness runs SQS reachability and backlog, the registered dependency
+// checks, and reads consumer progress and in-memory state.
+func (h *HealthHandler) checkReadiness(ctx context.Context) api.Readiness {
+	resp := api.Readiness{Status: "ready", Checks: []api.CheckResult{}}
+	checks := h.checks
+	var backlog, dlqBacklog queue.Backlog
+	if h.consumer != nil {
+		checks = append([]healthCheck{{name: h.consumer.Name(), fn: func(ctx context.Context) (err error) {
+			backlog, err = h.consumer.Backlog(ctx)
+			return err
+		}}}, checks...)
+	}
+	if h.dlq != nil {
+		checks = append(checks, healthCheck{name: "sqs_dlq", fn: func(ctx context.Context) (err error) {
+			dlqBacklog, err = h.dlq.Backlog(ctx)
+			return err
+		}})
+	}
+
+	resp.Checks = make([]api.CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			start := time.Now()
+			err := chk.fn(cctx)
+			res := api.CheckResult{Name: chk.name, OK: err == nil, LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				res.Error = err.Error()
+			}
+			resp.Checks[i] = res
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range resp.Checks {
+		switch {
+		case !r.OK:
+			resp.Reasons = append(resp.Reasons, r.Name+" unreachable")
+		case h.consumer != nil && r.Name == h.consumer.Name():
+			resp.Queue = &backlog
+		case r.Name == "sqs_dlq":
+			resp.DLQ = &dlqBacklog
+		}
+	}
+
+	if h.consumer != nil {
+		resp.Consumer = h.consumer.Activity()
+		if !resp.Consumer.Running {
+			resp.Reasons = append(resp.Reasons, "consumer not running")
+		}
+		last := resp.Consumer.LastProcessedAt
+		if !last.IsZero() {
+			resp.SinceLastProcessed = time.Since(last).Round(time.Second).String()
+		} else {
+			last = h.startedAt
+		}
+		if resp.Queue != nil && resp.Queue.Visible > 0 && time.Since(last) > processStallAfter {
+			resp.Reasons = append(resp.Reasons, "messages waiting but none processed recently")
+		}
+	}
+
+	if h.sync != nil {
+		st := h.sync()
+		resp.Sync = &st
+	}
+	if h.mem != nil {
+		resp.Memory.Alerts = h.mem.Len()
+	}
+	if h.sources != nil {
+		resp.Memory.Sources = h.sources.Len()
+	}
+
+	if len(resp.Reasons) > 0 {
+		resp.Status = "not_ready"
+	}
+	return resp
+}
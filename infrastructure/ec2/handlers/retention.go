@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+// SetRetention enables alert retention: the TTL set at ingest is extended when
+// an alert joins a source and shortened for false positives. Without it TTLs
+// are left as they are, except that the alerts of a confirmed incident always
+// lose theirs.
+func (h *Handler) SetRetention(r models.Retention) {
+	h.retention = &r
+}
+
+func (h *Handler) alertTTL(a models.Alert, attached, incident bool) int64 {
+	if h.retention == nil {
+		if attached && incident {
+			return 0
+		}
+		return a.TTL
+	}
+	return h.retention.TTL(a, attached, incident)
+}
+
+// retainSource recomputes the TTL of every alert of a source from their stored
+// state, so a status changed in the meantime is not overwritten.
+func (h *Handler) retainSource(ctx context.Context, sourceID string, keys []string) {
+	alerts, err := h.alerts.GetAlertsByKeys(ctx, keys)
+	if err != nil {
+		h.logger.Printf("retention: load alerts of source %s: %v", sourceID, err)
+		return
+	}
+	incident := models.ConfirmedIncident(alerts)
+	for _, a := range alerts {
+		ttl := h.alertTTL(a, true, incident)
+		if a.SourceID == sourceID && a.TTL == ttl {
+			continue
+		}
+		if err := h.alerts.SetAlertRetention(ctx, a.DeviceID, a.TS, sourceID, ttl); err != nil {
+			h.logger.Printf("retention: alert %s: %v", a.Key(), err)
+		}
+	}
+}
+
+// retainAlert updates retention after a status change of a. For an alert of a
+// source the whole source is recomputed: confirming one alert exempts all.
+func (h *Handler) retainAlert(ctx context.Context, a models.Alert) {
+	if a.SourceID != "" {
+		rec, err := h.store.GetSource(ctx, a.SourceID)
+		if err != nil {
+			h.logger.Printf("retention: load source %s: %v", a.SourceID, err)
+			return
+		}
+		if rec != nil {
+			h.retainSource(ctx, rec.ID, rec.AlertKeys)
+			return
+		}
+	}
+	ttl := h.alertTTL(a, a.SourceID != "", false)
+	if ttl == a.TTL {
+		return
+	}
+	if err := h.alerts.SetAlertRetention(ctx, a.DeviceID, a.TS, "", ttl); err != nil {
+		h.logger.Printf("retention: alert %s: %v", a.Key(), err)
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/export"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/metrics"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/stream"
+)
+
+// SetSensorThresholds sets after how long without an alert or heartbeat a
+// sensor is reported as stale and as offline.
+func (h *Handler) SetSensorThresholds(staleAfter, offlineAfter time.Duration) {
+	h.sensorStaleAfter = staleAfter
+	h.sensorOfflineAfter = offlineAfter
+}
+
+// SetPositions makes HandleEnvelope take the position of alerts sent without
+// lat/lon from the sensor registry.
+func (h *Handler) SetPositions(p processor.Positions) {
+	h.positions = p
+}
+
+// ListSensors handles GET /sensors[?status=online|stale|offline].
+func (h *Handler) ListSensors(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.SensorOnline, models.SensorStale, models.SensorOffline:
+	default:
+		c.JSON(400, gin.H{"error": "status must be online, stale or offline"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	sensors, err := h.devices.GetAllSensors(ctx)
+	if err != nil {
+		h.logger.Printf("GetAllSensors error: %v", err)
+		c.JSON(500, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.setSensorStatus(sensors, time.Now())
+	if status != "" {
+		kept := sensors[:0]
+		for _, s := range sensors {
+			if s.Status == status {
+				kept = append(kept, s)
+			}
+		}
+		sensors = kept
+	}
+
+	if wantsGeoJSON(c) {
+		writeGeoJSON(c, export.SensorsGeoJSON(sensors))
+		return
+	}
+	c.JSON(200, sensors)
+}
+
+func (h *Handler) setSensorStatus(sensors []models.Sensor, now time.Time) {
+	for i := range sensors {
+		sensors[i].Status = models.SensorStatus(sensors[i].LastActivity(), now, h.sensorStaleAfter, h.sensorOfflineAfter)
+	}
+}
+
+// RunSensorMonitor checks sensor status every interval and publishes
+// sensor.offline when a sensor goes offline and sensor.online when it comes
+// back. The first check only records the current state, so a restart does not
+// replay events for sensors that were already offline.
+func (h *Handler) RunSensorMonitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last map[string]string
+	for {
+		if next, err := h.checkSensors(ctx, last, time.Now()); err != nil {
+			h.logger.Printf("sensor monitor: %v", err)
+		} else {
+			last = next
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkSensors computes the current status of every sensor, publishes
+// transitions against prev (nil = first run, no events) and returns the new state.
+func (h *Handler) checkSensors(ctx context.Context, prev map[string]string, now time.Time) (map[string]string, error) {
+	sensors, err := h.devices.GetAllSensors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	h.setSensorStatus(sensors, now)
+
+	next := make(map[string]string, len(sensors))
+	counts := map[string]int{models.SensorOnline: 0, models.SensorStale: 0, models.SensorOffline: 0}
+	for _, s := range sensors {
+		next[s.DeviceID] = s.Status
+		counts[s.Status]++
+		if prev == nil {
+			continue
+		}
+
+		was, known := prev[s.DeviceID]
+		switch {
+		case s.Status == models.SensorOffline && known && was != models.SensorOffline:
+			h.logger.Printf("sensor %s went offline; last activity %s", s.DeviceID, s.LastActivity().Format(time.RFC3339))
+			h.events.Publish(stream.EventSensorOffline, s)
+		case s.Status != models.SensorOffline && was == models.SensorOffline:
+			h.logger.Printf("sensor %s is back %s", s.DeviceID, s.Status)
+			h.events.Publish(stream.EventSensorOnline, s)
+		}
+	}
+	for status, n := range counts {
+		metrics.Sensors.WithLabelValues(status).Set(float64(n))
+	}
+	return next, nil
+}
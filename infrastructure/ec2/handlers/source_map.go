@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+)
+
+const (
+	defaultMapSize = 1024
+	maxMapSize     = 4096
+)
+
+// SourceMap serves GET /sources/:id/map.png: the source with its contributing
+// alerts, e.g. for notification emails and reports. ?size= caps the longest side.
+func (h *Handler) SourceMap(c *gin.Context) {
+	size := defaultMapSize
+	if v := c.Query("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 64 || n > maxMapSize {
+			c.JSON(400, gin.H{"error": "size must be between 64 and 4096"})
+			return
+		}
+		size = n
+	}
+
+	sg, ok := h.findSource(c)
+	if !ok {
+		return
+	}
+	alerts := make([]*models.Alert, 0, len(sg.Alerts))
+	for _, a := range sg.Alerts {
+		if a != nil {
+			alerts = append(alerts, a)
+		}
+	}
+	if len(alerts) == 0 {
+		c.JSON(404, gin.H{"error": "source has no alerts to draw"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := processor.Render(&buf, alerts, []processor.SourceGroup{sg}, size); err != nil {
+		h.logger.Printf("render source %s error: %v", sg.ID, err)
+		c.JSON(500, gin.H{"error": "internal server error"})
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=60")
+	c.Data(200, "image/png", buf.Bytes())
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/api"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/export"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
+)
+
+// RestoreSources loads the sources that were active when the worker last
+// stopped, so a restart or deploy does not wipe the live view. Active sources
+// not updated within maxAge are not loaded but marked EXPIRED, otherwise
+// nothing would ever expire them.
+func (h *Handler) RestoreSources(ctx context.Context, maxAge time.Duration) error {
+	now := time.Now().UTC()
+	cutoff := now.Add(-maxAge)
+	recs, err := h.store.ListSources(ctx, models.SourceActive, cutoff, now)
+	if err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		sg, err := h.loadSource(ctx, rec)
+		if err != nil {
+			return err
+		}
+		h.sources.Add(sg)
+	}
+	h.logger.Printf("restored %d active sources", len(recs))
+
+	stale, err := h.store.ListSources(ctx, models.SourceActive, time.Time{}, cutoff.Add(-time.Second))
+	if err != nil {
+		return err
+	}
+	for _, rec := range stale {
+		if err := h.store.ExpireSource(ctx, rec.ID, now); err != nil {
+			h.logger.Printf("ExpireSource %s error: %v", rec.ID, err)
+		}
+	}
+	if len(stale) > 0 {
+		h.logger.Printf("expired %d stale active sources", len(stale))
+	}
+	return nil
+}
+
+func (h *Handler) loadSource(ctx context.Context, rec models.Source) (processor.SourceGroup, error) {
+	alerts, err := h.alerts.GetAlertsByKeys(ctx, rec.AlertKeys)
+	if err != nil {
+		return processor.SourceGroup{}, err
+	}
+	ptrs := make([]*models.Alert, len(alerts))
+	for i := range alerts {
+		ptrs[i] = &alerts[i]
+	}
+	return processor.SourceFromRecord(rec, ptrs), nil
+}
+
+func (h *Handler) saveSources(ctx context.Context, sources []processor.SourceGroup) {
+	for _, sg := range sources {
+		rec := sg.Record(models.SourceActive)
+		if err := h.store.SaveSource(ctx, rec); err != nil {
+			h.logger.Printf("SaveSource %s error: %v", sg.ID, err)
+			continue
+		}
+		h.retainSource(ctx, rec.ID, rec.AlertKeys)
+	}
+}
+
+// listStoredSources serves GET /sources?state=expired|active|all with optional
+// from/to (RFC3339, default last 24h) from the source store.
+func (h *Handler) listStoredSources(c *gin.Context, state string) {
+	ctx := c.Request.Context()
+
+	from, err := parseTime(c.Query("from"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid from"})
+		return
+	}
+	to, err := parseTime(c.Query("to"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid to"})
+		return
+	}
+	if to.IsZero() {
+		to = time.Now().UTC()
+	}
+	if from.IsZero() {
+		from = to.Add(-24 * time.Hour)
+	}
+
+	var states []string
+	switch state {
+	case "active":
+		states = []string{models.SourceActive}
+	case "expired":
+		states = []string{models.SourceExpired}
+	case "all":
+		states = []string{models.SourceActive, models.SourceExpired}
+	default:
+		c.JSON(400, gin.H{"error": "state must be active, expired or all"})
+		return
+	}
+
+	res := []models.Source{}
+	for _, st := range states {
+		recs, err := h.store.ListSources(ctx, st, from, to)
+		if err != nil {
+			h.logger.Printf("ListSources error: %v", err)
+			c.JSON(500, gin.H{"error": "internal server error"})
+			return
+		}
+		res = append(res, recs...)
+	}
+
+	if wantsGeoJSON(c) {
+		writeGeoJSON(c, export.StoredSourcesGeoJSON(res))
+		return
+	}
+	c.JSON(200, api.StoredSourceList{Count: len(res), Sources: res})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/stream"
+)
+
+type StreamHandler struct {
+	broker    *stream.Broker
+	heartbeat time.Duration
+}
+
+func NewStreamHandler(broker *stream.Broker, heartbeat time.Duration) *StreamHandler {
+	return &StreamHandler{broker: broker, heartbeat: heartbeat}
+}
+
+// Stream handles GET /stream as Server-Sent Events. Clients resume with the
+// Last-Event-ID header (or ?lastEventId=) and may narrow the feed with
+// ?types=alert.created,source.expired. When the requested events are no
+// longer buffered, or the ID comes from before a restart, a "reset" event tells
+// the client to refetch /alerts and /sources.
+func (h *StreamHandler) Stream(c *gin.Context) {
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("lastEventId")
+	}
+
+	client, backlog, complete := h.broker.Subscribe(lastID, splitList(c.Query("types")))
+	defer h.broker.Unsubscribe(client)
+
+	w := c.Writer
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+
+	fmt.Fprintf(w, "retry: 3000\n\n")
+	if !complete {
+		fmt.Fprintf(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, ev := range backlog {
+		writeEvent(c, ev)
+	}
+	w.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case ev, ok := <-client.Events():
+			if !ok {
+				return
+			}
+			writeEvent(c, ev)
+			w.Flush()
+		case <-ticker.C:
+			fmt.Fprintf(w, ": heartbeat %d\n\n", time.Now().Unix())
+			w.Flush()
+		}
+	}
+}
+
+func writeEvent(c *gin.Context, ev stream.Event) {
+	fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, ev.Data)
+}
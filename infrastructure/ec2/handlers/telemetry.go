@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/api"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+)
+
+const (
+	maxTelemetryRange = 31 * 24 * time.Hour
+	// automatic step keeps a series at roughly this many points
+	telemetryPoints = 500
+)
+
+var telemetrySteps = []time.Duration{
+	time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 3 * time.Hour, 6 * time.Hour, 24 * time.Hour,
+}
+
+// SetTelemetry enables GET /sensors/:id/telemetry.
+func (h *Handler) SetTelemetry(t *repository.TelemetryRepo) {
+	h.telemetry = t
+}
+
+// SensorTelemetry handles GET /sensors/:id/telemetry?from&to&step. The range
+// defaults to the last 24 h; step is a duration (>= 1m), "raw" for the samples
+// as stored, or picked automatically for about 500 points.
+func (h *Handler) SensorTelemetry(c *gin.Context) {
+	if h.telemetry == nil {
+		c.JSON(404, gin.H{"error": "telemetry not configured"})
+		return
+	}
+
+	from, to, step, err := parseTelemetryQuery(c, time.Now().UTC())
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	deviceID := c.Param("id")
+	samples, err := h.telemetry.Query(c.Request.Context(), deviceID, from, to)
+	if err != nil {
+		h.logger.Printf("telemetry query %s error: %v", deviceID, err)
+		c.JSON(500, gin.H{"error": "internal server error"})
+		return
+	}
+
+	resp := api.TelemetrySeries{
+		DeviceID: deviceID,
+		From:     from,
+		To:       to,
+		Samples:  len(samples),
+		Points:   models.Downsample(samples, step),
+	}
+	if step > 0 {
+		resp.Step = step.String()
+	}
+	if resp.Points == nil {
+		resp.Points = []models.Telemetry{}
+	}
+	c.JSON(200, resp)
+}
+
+func parseTelemetryQuery(c *gin.Context, now time.Time) (from, to time.Time, step time.Duration, err error) {
+	if from, err = parseTime(c.Query("from")); err != nil {
+		return from, to, 0, fmt.Errorf("invalid from: %w", err)
+	}
+	if to, err = parseTime(c.Query("to")); err != nil {
+		return from, to, 0, fmt.Errorf("invalid to: %w", err)
+	}
+	if to.IsZero() {
+		to = now
+	}
+	if from.IsZero() {
+		from = to.Add(-24 * time.Hour)
+	}
+	if from.After(to) {
+		return from, to, 0, errors.New("from must be before to")
+	}
+	if to.Sub(from) > maxTelemetryRange {
+		return from, to, 0, fmt.Errorf("range longer than %s", maxTelemetryRange)
+	}
+
+	switch v := c.Query("step"); v {
+	case "raw":
+		return from, to, 0, nil
+	case "":
+		step = telemetrySteps[len(telemetrySteps)-1]
+		for _, s := range telemetrySteps {
+			if to.Sub(from)/s <= telemetryPoints {
+				step = s
+				break
+			}
+		}
+	default:
+		if step, err = time.ParseDuration(v); err != nil || step < time.Minute {
+			return from, to, 0, errors.New("step must be a duration of at least 1m or \"raw\"")
+		}
+	}
+	return from, to, step, nil
+}
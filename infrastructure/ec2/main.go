@@ -2,24 +2,38 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sync"
 	"syscall"
 	"time"
 
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/auth"
 	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/config"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/edge"
 	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/handlers"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/metrics"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
 	processor "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/processor"
 	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/queue"
 	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/resilience"
 	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/router"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/stream"
 )
 
+// sources with no alert newer than this drop out of the live view
+const sourceMaxAge = 5 * time.Minute
+
 func main() {
 	ctx, cancel := signalContext()
 	defer cancel()
@@ -37,33 +51,220 @@ func main() {
 
 	sqsCli := sqs.NewFromConfig(awsCfg)
 	ddbCli := dynamodb.NewFromConfig(awsCfg)
+	s3Cli := s3.NewFromConfig(awsCfg)
+
+	rc := config.AppConfig.Resilience
+	sqsBreaker := resilience.NewBreaker("sqs", rc.FailureThreshold, rc.OpenTimeout)
+	ddbBreaker := resilience.NewBreaker("dynamodb", rc.FailureThreshold, rc.OpenTimeout)
+	s3Breaker := resilience.NewBreaker("s3", rc.FailureThreshold, rc.OpenTimeout)
+
+	cfg := config.AppConfig.AWS
+	ec := config.AppConfig.Edge
 
-	repo := repository.NewRepo(ddbCli, config.AppConfig.AWS.AlertsTable, config.AppConfig.AWS.DevicesTable)
+	repo := repository.NewRepo(ddbCli, ddbBreaker, cfg.AlertsTable, cfg.DevicesTable)
+	audio := repository.NewAudioRepo(s3Cli, s3Breaker, cfg.BucketName)
+
+	var cloudSources *repository.DynamoSourceStore
+	if t := cfg.SourcesTable; t != "" {
+		cloudSources = repository.NewDynamoSourceStore(ddbCli, ddbBreaker, t)
+	}
+
+	var alerts repository.AlertStore = repo
+	var devices repository.SensorStore = repo
+	var store repository.SourceStore = repository.NewMemorySourceStore()
+	var local *repository.BoltStore
+	switch {
+	case ec.Enabled:
+		// gateway: everything local, the cloud is only an upload target
+		if err := os.MkdirAll(ec.DataDir, 0o750); err != nil {
+			log.Fatal(err)
+		}
+		if local, err = repository.OpenBoltStore(filepath.Join(ec.DataDir, "edge.db")); err != nil {
+			log.Fatal(err)
+		}
+		defer local.Close()
+		alerts, devices, store = local, local, local
+		// clips stay in data_dir/audio until synced; the audio proxy reads S3 only
+		audio = nil
+		metrics.RegisterEdgeState(local.PendingCount)
+		logger.Printf("edge mode; gateway=%s data_dir=%s", ec.GatewayID, ec.DataDir)
+	case cloudSources != nil:
+		store = cloudSources
+	default:
+		logger.Printf("warning: sources_table not set; sources are kept in memory only")
+	}
 
 	// tu narazie ustawiasz ttl dla kazdego alertu
 	mem := processor.NewMemory(2 * time.Minute)
-	h := handlers.NewHandler(repo, mem, logger)
+	broker := stream.NewBroker(1000, 64)
+	sources := processor.NewSourceSet(processor.DefaultMinSharedAlerts)
+	metrics.RegisterState(mem.Len, sources.Len)
+	sensors := repository.NewSensorRegistry(devices, logger)
+	if err := sensors.Refresh(ctx); err != nil {
+		logger.Printf("warning: cannot load sensors: %v", err)
+	}
+	h := handlers.NewHandler(alerts, sensors, audio, store, mem, sources, broker, logger)
+	h.SetPositions(sensors)
+	zones := make(map[string]models.BBox, len(config.AppConfig.Zones))
+	for _, z := range config.AppConfig.Zones {
+		zones[z.Name] = models.BBox{MinLon: z.BBox[0], MinLat: z.BBox[1], MaxLon: z.BBox[2], MaxLat: z.BBox[3]}
+	}
+	h.SetZones(zones)
+	h.SetPublicBaseURL(config.AppConfig.Server.PublicBaseURL)
+	var telemetry *repository.TelemetryRepo
+	if t := cfg.TelemetryTable; t != "" && !ec.Enabled {
+		telemetry = repository.NewTelemetryRepo(ddbCli, ddbBreaker, t)
+		h.SetTelemetry(telemetry)
+	}
+	var retention *models.Retention
+	if rc := config.AppConfig.Retention; !rc.Disabled {
+		day := 24 * time.Hour
+		retention = &models.Retention{
+			FalsePositive: time.Duration(rc.FalsePositiveDays) * day,
+			Unattached:    time.Duration(rc.UnattachedDays) * day,
+			Attached:      time.Duration(rc.AttachedDays) * day,
+		}
+		h.SetRetention(*retention)
+	}
+	sc := config.AppConfig.Sensors
+	h.SetSensorThresholds(sc.StaleAfter, sc.OfflineAfter)
+	// stale restored sources are expired by the first CleanOldSources run
+	if err := h.RestoreSources(ctx, 7*24*time.Hour); err != nil {
+		logger.Printf("warning: cannot restore sources: %v", err)
+	}
 
+	var bg sync.WaitGroup
+	bg.Add(4)
 	go func() {
-		ticker := time.NewTicker(10 * time.Second)
-		defer ticker.Stop()
-		for range ticker.C {
-			h.CleanOldSources(5 * time.Minute)
-		}
+		defer bg.Done()
+		mem.RunPruner(ctx, 10*time.Second)
+	}()
+	go func() {
+		defer bg.Done()
+		h.RunSourceCleaner(ctx, 10*time.Second, sourceMaxAge)
+	}()
+	go func() {
+		defer bg.Done()
+		h.RunSensorMonitor(ctx, sc.CheckInterval)
+	}()
+	go func() {
+		defer bg.Done()
+		sensors.Run(ctx, sc.RefreshInterval)
 	}()
 
-	r := router.SetupRouter(h)
+	queues := map[string]*queue.DLQ{}
+	if cfg.DLQURL != "" && !ec.Enabled {
+		queues["dlq"] = queue.NewDLQ(sqsCli, cfg.DLQURL, cfg.SQSURL)
+	}
+	if cfg.QuarantineURL != "" && !ec.Enabled {
+		queues["quarantine"] = queue.NewDLQ(sqsCli, cfg.QuarantineURL, cfg.SQSURL)
+	}
+	admin := handlers.NewAdminHandler(queues, logger)
+
+	authn := auth.Disabled()
+	if ac := config.AppConfig.Auth; ac.Disabled {
+		logger.Printf("warning: authentication disabled; every request is treated as admin")
+	} else {
+		keys, err := auth.NewKeySet(ctx, ac.JWKS, logger)
+		if err != nil {
+			log.Fatal(err)
+		}
+		authn = auth.New(keys, auth.Options{Issuer: ac.Issuer, Audience: ac.Audience, RolesClaim: ac.RolesClaim})
+		bg.Add(1)
+		go func() {
+			defer bg.Done()
+			keys.Run(ctx, ac.JWKSRefresh)
+		}()
+	}
+
+	srvCfg := config.AppConfig.Server
+	var worker handlers.QueueWorker
+	var runWorker func(context.Context)
+	var ingest *edge.Ingest
+	var syncer *edge.Syncer
+	if ec.Enabled {
+		q, err := edge.NewQueue(local.DB(), h.HandleEnvelope, logger)
+		if err != nil {
+			log.Fatal(err)
+		}
+		worker, runWorker = q, q.Run
+		ingest = edge.NewIngest(local, q, filepath.Join(ec.DataDir, "audio"), ec.IngestToken, logger)
+		if retention != nil {
+			ingest.SetRetention(*retention)
+		}
+
+		var upstream repository.SourceStore
+		if cloudSources != nil {
+			upstream = cloudSources
+		}
+		syncer = edge.NewSyncer(local, ddbCli, s3Cli, upstream, edge.SyncConfig{
+			GatewayID:    ec.GatewayID,
+			AlertsTable:  cfg.AlertsTable,
+			DevicesTable: cfg.DevicesTable,
+			Bucket:       cfg.BucketName,
+			AudioDir:     filepath.Join(ec.DataDir, "audio"),
+			BatchSize:    ec.SyncBatch,
+		}, logger)
+		bg.Add(1)
+		go func() {
+			defer bg.Done()
+			syncer.Run(ctx, ec.SyncInterval)
+		}()
+	} else {
+		consumer := queue.NewConsumer(sqsCli, cfg.SQSURL, cfg.QuarantineURL, h.HandleEnvelope, logger)
+		consumer.SetDrainTimeout(srvCfg.DrainTimeout)
+		consumer.SetBreakers(sqsBreaker, ddbBreaker)
+		worker, runWorker = consumer, consumer.Run
+	}
+
+	health := handlers.NewHealthHandler(worker, mem, sources, sqsBreaker, ddbBreaker, s3Breaker)
+	if ec.Enabled {
+		// the uplink may be down for days; that must not make the gateway unready
+		health.AddCheck("edge_store", local.Ping)
+		health.SetSync(syncer.Status)
+	} else {
+		health.AddCheck("dynamodb", repo.Ping)
+		if cloudSources != nil {
+			health.AddCheck("dynamodb_sources", cloudSources.Ping)
+		}
+		health.AddCheck("s3", audio.Ping)
+	}
+	if telemetry != nil {
+		health.AddCheck("dynamodb_telemetry", telemetry.Ping)
+	}
+	if d := queues["dlq"]; d != nil {
+		health.SetDLQ(d)
+	}
+	events := handlers.NewStreamHandler(broker, 15*time.Second)
+	srv := &http.Server{
+		Addr:    srvCfg.Addr,
+		Handler: router.SetupRouter(h, admin, health, events, ingest, authn, srvCfg.CORSOrigins),
+	}
 	go func() {
-		port := ":8080"
-		logger.Printf("HTTP server listening on %s", port)
-		if err := r.Run(port); err != nil {
-			logger.Fatalf("HTTP server error: %v", err)
+		logger.Printf("HTTP server listening on %s", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Printf("HTTP server error: %v", err)
+			cancel()
 		}
 	}()
-	consumer := queue.NewConsumer(sqsCli, config.AppConfig.AWS.SQSURL, h.HandleEnvelope, logger)
 
-	logger.Printf("worker online; queue=%s table=%s", config.AppConfig.AWS.SQSURL, config.AppConfig.AWS.AlertsTable)
-	consumer.Run(ctx)
+	if ec.Enabled {
+		logger.Printf("worker online; local queue, gateway=%s", ec.GatewayID)
+	} else {
+		logger.Printf("worker online; queue=%s table=%s", cfg.SQSURL, cfg.AlertsTable)
+	}
+	runWorker(ctx)
+
+	logger.Printf("shutting down; waiting up to %s for HTTP requests", srvCfg.ShutdownTimeout)
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), srvCfg.ShutdownTimeout)
+	defer cancelShutdown()
+	broker.Close()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Printf("HTTP shutdown error: %v", err)
+	}
+
+	bg.Wait()
+	logger.Printf("worker stopped")
 }
 
 func signalContext() (context.Context, context.CancelFunc) {
@@ -73,6 +274,9 @@ func signalContext() (context.Context, context.CancelFunc) {
 	go func() {
 		<-ch
 		cancel()
+		// a second signal skips the graceful drain
+		<-ch
+		os.Exit(1)
 	}()
 	return ctx, cancel
 }
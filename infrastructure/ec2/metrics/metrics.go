@@ -0,0 +1,148 @@
+// Package metrics defines the Prometheus metrics of the worker. Everything is
+// registered in Registry (not the global default) and served on GET /metrics.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "forest"
+
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+// SQS consumer
+var (
+	MessagesReceived = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "queue", Name: "messages_received_total",
+		Help: "Messages received from the alerts queue.",
+	})
+	MessagesProcessed = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "queue", Name: "messages_processed_total",
+		Help: "Messages handled successfully and deleted.",
+	})
+	// reason is "handler" for retried handler errors, "dead_letter" for messages
+	// the edge queue gave up on, or a quarantine reason code
+	MessagesFailed = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "queue", Name: "messages_failed_total",
+		Help: "Messages that failed decoding or handling, by reason.",
+	}, []string{"reason"})
+)
+
+// alert pipeline
+var (
+	HandleDuration = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace, Subsystem: "pipeline", Name: "handle_envelope_duration_seconds",
+		Help:    "Time spent in HandleEnvelope (DynamoDB read, grouping, source upserts).",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	})
+	// alert ts (set by the sensor) to the moment the worker picked it up
+	AlertDelay = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace, Subsystem: "pipeline", Name: "alert_delay_seconds",
+		Help:    "End-to-end delay from alert timestamp to processing by the worker.",
+		Buckets: []float64{.25, .5, 1, 2, 5, 10, 30, 60, 120, 300, 900},
+	})
+	MemoryPruned = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "memory", Name: "pruned_total",
+		Help: "Alerts dropped from memory after their TTL.",
+	})
+	FindSourcesDuration = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace, Subsystem: "localization", Name: "find_sources_duration_seconds",
+		Help:    "Runtime of FindPotentialSources.",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+	})
+	// result is "valid" or "rejected"
+	Cliques = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "localization", Name: "cliques_total",
+		Help: "Maximal cliques of overlapping alerts found, by validation result.",
+	}, []string{"result"})
+)
+
+// sensors by computed status (online, stale, offline)
+var Sensors = factory.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace, Name: "sensors",
+	Help: "Registered sensors by status, as of the last sensor monitor check.",
+}, []string{"status"})
+
+// edge gateway sync; kind is alert, sensor or source
+var (
+	EdgeSynced = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "edge", Name: "synced_total",
+		Help: "Records uploaded from the gateway to the cloud, by kind.",
+	}, []string{"kind"})
+	EdgeConflicts = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "edge", Name: "sync_conflicts_total",
+		Help: "Alerts already present in the cloud when synced, by winning side.",
+	}, []string{"winner"})
+)
+
+// RegisterEdgeState exposes the number of records waiting for upload.
+func RegisterEdgeState(pending func() int) {
+	factory.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "edge", Name: "sync_pending",
+		Help: "Records changed on the gateway and not uploaded yet.",
+	}, func() float64 { return float64(pending()) })
+}
+
+// HTTP API
+var HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace, Subsystem: "http", Name: "request_duration_seconds",
+	Help:    "HTTP request latency by route template, method and status.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"method", "route", "status"})
+
+func init() {
+	Registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+}
+
+// RegisterState exposes the size of the in-memory alert window and of the live
+// source set, read at scrape time.
+func RegisterState(alerts, sources func() int) {
+	factory.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "memory", Name: "alerts",
+		Help: "Alerts currently held in the in-memory window.",
+	}, func() float64 { return float64(alerts()) })
+	factory.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "memory", Name: "sources",
+		Help: "Sources currently in the live view.",
+	}, func() float64 { return float64(sources()) })
+}
+
+// Handler serves the registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records HTTP latencies. Routes are labelled with the gin template
+// (/sources/:id) so ids do not blow up the label cardinality; unmatched
+// requests are grouped under "unmatched". Long-lived streams are skipped.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		switch route {
+		case "/stream", "/metrics":
+			return
+		case "":
+			route = "unmatched"
+		}
+		HTTPDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// Since observes the time elapsed since start in h; use with defer.
+func Since(h prometheus.Observer, start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
@@ -5,6 +5,52 @@ type Envelope struct {
 	TS       string `json:"ts"`
 }
 
+// Alert statuses. Every alert starts as NEW (set by lambda-alert) and is moved
+// through the workflow by operators.
+const (
+	StatusNew           = "NEW"
+	StatusAcknowledged  = "ACKNOWLEDGED"
+	StatusInvestigating = "INVESTIGATING"
+	StatusResolved      = "RESOLVED"
+	StatusFalsePositive = "FALSE_POSITIVE"
+)
+
+var statusTransitions = map[string][]string{
+	StatusNew:           {StatusAcknowledged, StatusInvestigating, StatusResolved, StatusFalsePositive},
+	StatusAcknowledged:  {StatusInvestigating, StatusResolved, StatusFalsePositive},
+	StatusInvestigating: {StatusResolved, StatusFalsePositive},
+	// reopen
+	StatusResolved:      {StatusInvestigating},
+	StatusFalsePositive: {StatusInvestigating},
+}
+
+func ValidStatus(s string) bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+// CanTransition reports whether an alert may move from one status to another.
+// An empty status is treated as NEW.
+func CanTransition(from, to string) bool {
+	if from == "" {
+		from = StatusNew
+	}
+	for _, s := range statusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+type StatusChange struct {
+	From     string `dynamodbav:"from"     json:"from"`
+	To       string `dynamodbav:"to"       json:"to"`
+	Note     string `dynamodbav:"note"     json:"note,omitempty"`
+	Operator string `dynamodbav:"operator" json:"operator"`
+	At       string `dynamodbav:"at"       json:"at"`
+}
+
 type Alert struct {
 	DeviceID  string  `dynamodbav:"deviceId"  json:"deviceId"`
 	TS        string  `dynamodbav:"ts"        json:"ts"`
@@ -16,4 +62,26 @@ type Alert struct {
 	CreatedAt string  `dynamodbav:"createdAt" json:"createdAt"`
 	// trzeba bedzie dodac
 	Distance float64 `dynamodbav:"distance" json:"distance"` // !!!!!
+	// klasa dzwieku z klasyfikatora na czujniku (np. chainsaw), opcjonalna
+	Class string `dynamodbav:"class,omitempty" json:"class,omitempty"`
+
+	History   []StatusChange `dynamodbav:"history,omitempty"   json:"history,omitempty"`
+	UpdatedAt string         `dynamodbav:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+
+	// id of the edge gateway that processed the alert before syncing it to the
+	// cloud; such alerts are not queued again (lambda-enqueuer skips them)
+	Origin string `dynamodbav:"origin,omitempty" json:"origin,omitempty"`
+
+	// source the alert was attached to by the worker (last one if several)
+	SourceID string `dynamodbav:"sourceId,omitempty" json:"sourceId,omitempty"`
+	// DynamoDB TTL (epoch seconds) set by Retention; 0 = kept forever
+	TTL int64 `dynamodbav:"ttl,omitempty" json:"ttl,omitempty"`
+
+	// sciezka do proxy audio w API, nie zapisywana w bazie
+	AudioURL string `dynamodbav:"-" json:"audioUrl,omitempty"`
+}
+
+// Key identifies an alert the same way as the table's primary key.
+func (a *Alert) Key() string {
+	return a.DeviceID + "#" + a.TS
 }
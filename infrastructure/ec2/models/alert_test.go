@@ -0,0 +1,62 @@
+package models
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	cases := []struct {
+		from, to string
+		ok       bool
+	}{
+		{StatusNew, StatusAcknowledged, true},
+		{"", StatusAcknowledged, true},
+		{StatusAcknowledged, StatusInvestigating, true},
+		{StatusInvestigating, StatusResolved, true},
+		{StatusInvestigating, StatusFalsePositive, true},
+		{StatusResolved, StatusInvestigating, true},
+		{StatusResolved, StatusNew, false},
+		{StatusInvestigating, StatusAcknowledged, false},
+		{StatusNew, StatusNew, false},
+		{StatusNew, "DONE", false},
+		{"processed", StatusAcknowledged, false},
+	}
+	for _, tc := range cases {
+		if got := CanTransition(tc.from, tc.to); got != tc.ok {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", tc.from, tc.to, got, tc.ok)
+		}
+	}
+}
+
+func TestRetentionTTL(t *testing.T) {
+	day := int64(24 * 3600)
+	base := int64(1764792000) // 2025-12-03T20:00:00Z
+	r := DefaultRetention
+	a := Alert{DeviceID: "A", TS: "2025-12-03T20:00:00Z", Status: StatusNew}
+	fp := a
+	fp.Status = StatusFalsePositive
+
+	cases := []struct {
+		name               string
+		a                  Alert
+		attached, incident bool
+		want               int64
+	}{
+		{"unattached", a, false, false, base + 30*day},
+		{"attached", a, true, false, base + 180*day},
+		{"false positive", fp, true, false, base + 14*day},
+		{"confirmed incident", a, true, true, 0},
+		{"false positive in confirmed incident", fp, true, true, 0},
+		{"bad ts", Alert{TS: "yesterday"}, false, false, 0},
+	}
+	for _, tc := range cases {
+		if got := r.TTL(tc.a, tc.attached, tc.incident); got != tc.want {
+			t.Errorf("%s: TTL = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+
+	if ConfirmedIncident([]Alert{a, fp}) {
+		t.Error("NEW + FALSE_POSITIVE is not a confirmed incident")
+	}
+	if !ConfirmedIncident([]Alert{a, {Status: StatusResolved}}) {
+		t.Error("a RESOLVED alert confirms the incident")
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Retention says how long an alert stays in the alerts table, counted from its
+// ts. DynamoDB TTL deletes it afterwards and lambda-archiver moves it to S3.
+//
+//   - FalsePositive: operators marked the alert FALSE_POSITIVE,
+//   - Unattached: the alert never became part of a source (set at ingest),
+//   - Attached: the alert belongs to a source,
+//   - alerts of a confirmed incident (see ConfirmedIncident) never expire.
+type Retention struct {
+	FalsePositive time.Duration
+	Unattached    time.Duration
+	Attached      time.Duration
+}
+
+var DefaultRetention = Retention{
+	FalsePositive: 14 * 24 * time.Hour,
+	Unattached:    30 * 24 * time.Hour,
+	Attached:      180 * 24 * time.Hour,
+}
+
+// TTL returns the TTL attribute for a, or 0 when a must be kept. attached and
+// incident describe the source a belongs to.
+func (r Retention) TTL(a Alert, attached, incident bool) int64 {
+	if attached && incident {
+		return 0
+	}
+	ts, err := time.Parse(time.RFC3339, a.TS)
+	if err != nil {
+		// keep what cannot be dated rather than expire it at once
+		return 0
+	}
+	keep := r.Unattached
+	switch {
+	case a.Status == StatusFalsePositive:
+		keep = r.FalsePositive
+	case attached:
+		keep = r.Attached
+	}
+	return ts.Add(keep).Unix()
+}
+
+// ConfirmedIncident reports whether operators confirmed the source made of
+// alerts: at least one of them is being investigated or was resolved.
+func ConfirmedIncident(alerts []Alert) bool {
+	for _, a := range alerts {
+		if a.Status == StatusInvestigating || a.Status == StatusResolved {
+			return true
+		}
+	}
+	return false
+}
@@ -2,10 +2,45 @@ package models
 
 import "time"
 
+const (
+	SensorOnline  = "online"
+	SensorStale   = "stale"
+	SensorOffline = "offline"
+)
+
 type Sensor struct {
-	DeviceID  string    `json:"deviceId"`
-	FirstSeen time.Time `json:"firstSeen"`
-	LastSeen  time.Time `json:"lastSeen"`
-	Lat       float64   `json:"lat"`
-	Lon       float64   `json:"lon"`
+	DeviceID      string    `json:"deviceId"`
+	FirstSeen     time.Time `json:"firstSeen"`
+	LastSeen      time.Time `json:"lastSeen"`
+	LastHeartbeat time.Time `json:"lastHeartbeat,omitzero"`
+	Lat           float64   `json:"lat"`
+	Lon           float64   `json:"lon"`
+	// latest values reported with a heartbeat
+	Telemetry *Telemetry `json:"telemetry,omitempty"`
+	// computed by the worker from LastActivity, not stored
+	Status string `json:"status,omitempty"`
+}
+
+// LastActivity is the newer of the last alert (LastSeen) and the last heartbeat.
+func (s Sensor) LastActivity() time.Time {
+	if s.LastHeartbeat.After(s.LastSeen) {
+		return s.LastHeartbeat
+	}
+	return s.LastSeen
+}
+
+// SensorStatus classifies a sensor by how long ago it was last heard from. A
+// sensor that has never reported anything is offline.
+func SensorStatus(lastActivity, now time.Time, staleAfter, offlineAfter time.Duration) string {
+	if lastActivity.IsZero() {
+		return SensorOffline
+	}
+	switch age := now.Sub(lastActivity); {
+	case age >= offlineAfter:
+		return SensorOffline
+	case age >= staleAfter:
+		return SensorStale
+	default:
+		return SensorOnline
+	}
 }
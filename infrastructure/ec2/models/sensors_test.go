@@ -0,0 +1,41 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSensorStatus(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{0, SensorOnline},
+		{4 * time.Minute, SensorOnline},
+		{5 * time.Minute, SensorStale},
+		{14 * time.Minute, SensorStale},
+		{15 * time.Minute, SensorOffline},
+		{-time.Minute, SensorOnline}, // sensor clock ahead
+	}
+	for _, tc := range cases {
+		if got := SensorStatus(now.Add(-tc.ago), now, 5*time.Minute, 15*time.Minute); got != tc.want {
+			t.Errorf("%s ago: got %s, want %s", tc.ago, got, tc.want)
+		}
+	}
+	if got := SensorStatus(time.Time{}, now, 5*time.Minute, 15*time.Minute); got != SensorOffline {
+		t.Errorf("never seen: got %s, want offline", got)
+	}
+}
+
+func TestLastActivity(t *testing.T) {
+	alert := time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC)
+	s := Sensor{LastSeen: alert}
+	if !s.LastActivity().Equal(alert) {
+		t.Fatalf("without heartbeat: %s", s.LastActivity())
+	}
+	s.LastHeartbeat = alert.Add(30 * time.Minute)
+	if !s.LastActivity().Equal(s.LastHeartbeat) {
+		t.Fatalf("with newer heartbeat: %s", s.LastActivity())
+	}
+}
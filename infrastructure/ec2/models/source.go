@@ -0,0 +1,21 @@
+package models
+
+const (
+	SourceActive  = "ACTIVE"
+	SourceExpired = "EXPIRED"
+)
+
+// Source is the stored form of a localized sound source. Alerts are kept as
+// keys (deviceId#ts) only; the alerts themselves live in the alerts table.
+type Source struct {
+	ID          string   `dynamodbav:"id"                  json:"id"`
+	Lat         float64  `dynamodbav:"lat"                 json:"lat"`
+	Lon         float64  `dynamodbav:"lon"                 json:"lon"`
+	Residual    float64  `dynamodbav:"residual,omitempty"  json:"residual,omitempty"`
+	AlertKeys   []string `dynamodbav:"alertKeys"           json:"alertKeys"`
+	State       string   `dynamodbav:"state"               json:"state"`
+	FirstSeen   string   `dynamodbav:"firstSeen"           json:"firstSeen"`
+	UpdatedAt   string   `dynamodbav:"updatedAt"           json:"updatedAt"`
+	LastAlertAt string   `dynamodbav:"lastAlertAt"         json:"lastAlertAt"`
+	ExpiredAt   string   `dynamodbav:"expiredAt,omitempty" json:"expiredAt,omitempty"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// Telemetry is one sample reported by a sensor with its heartbeat. Fields the
+// sensor did not report are nil. In downsampled series each field is the mean
+// of the bucket and Samples is the number of raw samples in it.
+type Telemetry struct {
+	TS            string   `json:"ts" dynamodbav:"ts"`
+	BatteryV      *float64 `json:"batteryV,omitempty" dynamodbav:"batteryV,omitempty"`
+	TemperatureC  *float64 `json:"temperatureC,omitempty" dynamodbav:"temperatureC,omitempty"`
+	RSSI          *float64 `json:"rssi,omitempty" dynamodbav:"rssi,omitempty"`
+	FreeStorageMB *float64 `json:"freeStorageMb,omitempty" dynamodbav:"freeStorageMb,omitempty"`
+	NoiseFloorDB  *float64 `json:"noiseFloorDb,omitempty" dynamodbav:"noiseFloorDb,omitempty"`
+	Samples       int      `json:"samples,omitempty" dynamodbav:"-"`
+}
+
+func (t *Telemetry) fields() []**float64 {
+	return []**float64{&t.BatteryV, &t.TemperatureC, &t.RSSI, &t.FreeStorageMB, &t.NoiseFloorDB}
+}
+
+// Downsample averages samples into buckets of step aligned to the Unix epoch,
+// sorted by time. Each field is averaged over the samples that reported it.
+// Samples with an unparsable ts are skipped; step <= 0 returns samples as is.
+func Downsample(samples []Telemetry, step time.Duration) []Telemetry {
+	if step <= 0 {
+		return samples
+	}
+
+	type acc struct {
+		sum   [5]float64
+		count [5]int
+		n     int
+	}
+	buckets := map[int64]*acc{}
+	for _, s := range samples {
+		ts, err := time.Parse(time.RFC3339, s.TS)
+		if err != nil {
+			continue
+		}
+		key := ts.Truncate(step).Unix()
+		b := buckets[key]
+		if b == nil {
+			b = &acc{}
+			buckets[key] = b
+		}
+		b.n++
+		for i, f := range s.fields() {
+			if *f != nil {
+				b.sum[i] += **f
+				b.count[i]++
+			}
+		}
+	}
+
+	keys := make([]int64, 0, len(buckets))
+	for k := range buckets {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	out := make([]Telemetry, 0, len(keys))
+	for _, k := range keys {
+		b := buckets[k]
+		t := Telemetry{TS: time.Unix(k, 0).UTC().Format(time.RFC3339), Samples: b.n}
+		for i, f := range t.fields() {
+			if b.count[i] > 0 {
+				mean := b.sum[i] / float64(b.count[i])
+				*f = &mean
+			}
+		}
+		out = append(out, t)
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func f(v float64) *float64 { return &v }
+
+func TestDownsample(t *testing.T) {
+	samples := []Telemetry{
+		{TS: "2026-10-19T10:04:00Z", BatteryV: f(3.6), RSSI: f(-80)},
+		{TS: "2026-10-19T10:01:00Z", BatteryV: f(3.8)},
+		{TS: "2026-10-19T10:05:00Z", BatteryV: f(3.5), TemperatureC: f(7)},
+		{TS: "not a time", BatteryV: f(0)},
+	}
+
+	got := Downsample(samples, 5*time.Minute)
+	if len(got) != 2 {
+		t.Fatalf("got %d buckets, want 2: %+v", len(got), got)
+	}
+
+	first := got[0]
+	if first.TS != "2026-10-19T10:00:00Z" || first.Samples != 2 {
+		t.Errorf("first bucket = %s (%d samples)", first.TS, first.Samples)
+	}
+	if first.BatteryV == nil || *first.BatteryV < 3.699 || *first.BatteryV > 3.701 {
+		t.Errorf("battery mean = %v, want 3.7", first.BatteryV)
+	}
+	// only one sample reported rssi, the mean is over that one
+	if first.RSSI == nil || *first.RSSI != -80 {
+		t.Errorf("rssi = %v, want -80", first.RSSI)
+	}
+	if first.TemperatureC != nil {
+		t.Errorf("temperature = %v, want nil (not reported)", *first.TemperatureC)
+	}
+
+	if got[1].TS != "2026-10-19T10:05:00Z" || got[1].TemperatureC == nil || *got[1].TemperatureC != 7 {
+		t.Errorf("second bucket = %+v", got[1])
+	}
+}
+
+func TestDownsampleZeroStep(t *testing.T) {
+	samples := []Telemetry{{TS: "2026-10-19T10:04:00Z"}, {TS: "2026-10-19T10:01:00Z"}}
+	if got := Downsample(samples, 0); len(got) != 2 || got[0].TS != samples[0].TS {
+		t.Fatalf("step 0 should return samples unchanged, got %+v", got)
+	}
+}
@@ -1,10 +1,12 @@
 package processor
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/metrics"
 	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
 )
 
@@ -27,9 +29,6 @@ func NewMemory(ttl time.Duration) *Memory {
 
 	fmt.Printf("[Memory] Initialized with TTL=%s\n", ttl)
 
-	// Background cleaner
-	go m.backgroundPrune()
-
 	return m
 }
 
@@ -37,7 +36,7 @@ func (m *Memory) Add(a *models.Alert) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	key := a.DeviceID + "#" + a.TS
+	key := a.Key()
 	m.alerts[key] = AlertEntry{
 		Alert:    a,
 		Received: time.Now(),
@@ -59,6 +58,12 @@ func (m *Memory) GetAll() []*models.Alert {
 	return res
 }
 
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.alerts)
+}
+
 func (m *Memory) Prune() {
 	cutoff := time.Now().Add(-m.ttl)
 
@@ -75,17 +80,25 @@ func (m *Memory) Prune() {
 	}
 
 	after := len(m.alerts)
+	metrics.MemoryPruned.Add(float64(before - after))
 	fmt.Printf("[Memory] Prune completed: before=%d after=%d pruned=%d\n", before, after, before-after)
 }
 
-func (m *Memory) backgroundPrune() {
-	ticker := time.NewTicker(10 * time.Second)
+// RunPruner prunes expired alerts every interval until ctx is cancelled.
+func (m *Memory) RunPruner(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	fmt.Printf("[Memory] Background prune started (interval=10s)\n")
+	fmt.Printf("[Memory] Background prune started (interval=%s)\n", interval)
 
-	for range ticker.C {
-		fmt.Printf("[Memory] Running scheduled prune\n")
-		m.Prune()
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Printf("[Memory] Background prune stopped\n")
+			return
+		case <-ticker.C:
+			fmt.Printf("[Memory] Running scheduled prune\n")
+			m.Prune()
+		}
 	}
 }
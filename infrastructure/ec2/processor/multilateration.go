@@ -0,0 +1,149 @@
+package processor
+
+import (
+	"math"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+const (
+	earthRadius = 6371e3
+	// Levenberg-Marquardt stops when a step moves the estimate less than this (m)
+	lmTolerance = 0.01
+	lmMaxIter   = 100
+)
+
+// enu is a local east-north plane (meters) tangent at a reference point. Over
+// the few kilometers a clique spans the error against Haversine is negligible.
+type enu struct {
+	lat0, lon0, cosLat0 float64
+}
+
+func newENU(lat0, lon0 float64) enu {
+	return enu{lat0: lat0, lon0: lon0, cosLat0: math.Cos(lat0 * math.Pi / 180)}
+}
+
+func (p enu) forward(lat, lon float64) (x, y float64) {
+	x = (lon - p.lon0) * math.Pi / 180 * earthRadius * p.cosLat0
+	y = (lat - p.lat0) * math.Pi / 180 * earthRadius
+	return x, y
+}
+
+func (p enu) inverse(x, y float64) (lat, lon float64) {
+	lat = p.lat0 + y/earthRadius*180/math.Pi
+	lon = p.lon0 + x/(earthRadius*p.cosLat0)*180/math.Pi
+	return lat, lon
+}
+
+// Multilaterate estimates the source position from the sensor positions and
+// the distances they reported: least squares on the range residuals
+// |source - sensor| - distance, solved with Levenberg-Marquardt in a local
+// east-north plane, starting from the centroid of the sensors. residual is the
+// RMS of the final range residuals in meters. With fewer than 3 alerts that
+// carry a distance there is nothing to solve and ok is false.
+func Multilaterate(alerts []*models.Alert) (lat, lon, residual float64, ok bool) {
+	var used []*models.Alert
+	for _, a := range alerts {
+		if a != nil && a.Distance > 0 {
+			used = append(used, a)
+		}
+	}
+	if len(used) < 3 {
+		return 0, 0, 0, false
+	}
+
+	lat0, lon0 := centroid(used)
+	proj := newENU(lat0, lon0)
+	sx := make([]float64, len(used))
+	sy := make([]float64, len(used))
+	for i, a := range used {
+		sx[i], sy[i] = proj.forward(a.Lat, a.Lon)
+	}
+
+	cost := func(x, y float64) float64 {
+		c := 0.0
+		for i, a := range used {
+			r := math.Hypot(x-sx[i], y-sy[i]) - a.Distance
+			c += r * r
+		}
+		return c
+	}
+
+	x, y := 0.0, 0.0 // the centroid
+	c := cost(x, y)
+	lambda := 1e-3
+	for iter := 0; iter < lmMaxIter; iter++ {
+		// normal equations J^T J and J^T r; the row of J for sensor i is the
+		// unit vector from the sensor to the estimate
+		var a11, a12, a22, g1, g2 float64
+		for i, a := range used {
+			dx, dy := x-sx[i], y-sy[i]
+			d := math.Hypot(dx, dy)
+			r := d - a.Distance
+			if d < 1e-9 {
+				// estimate on top of a sensor: the direction is undefined
+				dx, dy, d = 1, 0, 1
+			}
+			jx, jy := dx/d, dy/d
+			a11 += jx * jx
+			a12 += jx * jy
+			a22 += jy * jy
+			g1 += jx * r
+			g2 += jy * r
+		}
+
+		// raise the damping until the step lowers the cost
+		var stepX, stepY float64
+		found := false
+		for ; lambda < 1e10; lambda *= 10 {
+			m11, m22 := a11*(1+lambda), a22*(1+lambda)
+			det := m11*m22 - a12*a12
+			if math.Abs(det) < 1e-12 {
+				continue
+			}
+			stepX = -(m22*g1 - a12*g2) / det
+			stepY = -(m11*g2 - a12*g1) / det
+			if nc := cost(x+stepX, y+stepY); nc < c {
+				c, found = nc, true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+		x, y = x+stepX, y+stepY
+		lambda /= 10
+		if math.Hypot(stepX, stepY) < lmTolerance {
+			break
+		}
+	}
+
+	lat, lon = proj.inverse(x, y)
+	return lat, lon, math.Sqrt(c / float64(len(used))), true
+}
+
+// locate places a group of alerts: multilateration when enough distances are
+// known, otherwise the centroid of the sensors.
+func locate(alerts []*models.Alert) (lat, lon, residual float64) {
+	if lat, lon, residual, ok := Multilaterate(alerts); ok {
+		return lat, lon, residual
+	}
+	lat, lon = centroid(alerts)
+	return lat, lon, 0
+}
+
+func centroid(alerts []*models.Alert) (lat, lon float64) {
+	n := 0
+	for _, a := range alerts {
+		if a == nil {
+			continue
+		}
+		lat += a.Lat
+		lon += a.Lon
+		n++
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return lat / float64(n), lon / float64(n)
+}
@@ -0,0 +1,82 @@
+package processor
+
+import (
+	"math"
+	"testing"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+// sensorsAround places sensors at the given east/north offsets (m) from a
+// reference point and sets their distance to the source at (srcX, srcY).
+func sensorsAround(srcX, srcY float64, noise []float64, offsets ...[2]float64) []*models.Alert {
+	proj := newENU(50.06, 19.94)
+	alerts := make([]*models.Alert, len(offsets))
+	for i, o := range offsets {
+		lat, lon := proj.inverse(o[0], o[1])
+		d := math.Hypot(srcX-o[0], srcY-o[1])
+		if i < len(noise) {
+			d += noise[i]
+		}
+		alerts[i] = &models.Alert{DeviceID: string(rune('A' + i)), Lat: lat, Lon: lon, Distance: d}
+	}
+	return alerts
+}
+
+func TestMultilaterateOutsideHull(t *testing.T) {
+	// triangle of sensors, chainsaw well north-east of all of them
+	triangle := [][2]float64{{0, 0}, {300, 0}, {0, 300}}
+	alerts := sensorsAround(600, 500, nil, triangle...)
+	srcLat, srcLon := newENU(50.06, 19.94).inverse(600, 500)
+
+	lat, lon, residual, ok := Multilaterate(alerts)
+	if !ok {
+		t.Fatal("not solved")
+	}
+	if miss := Distance(lat, lon, srcLat, srcLon); miss > 1 {
+		t.Fatalf("estimate %.6f,%.6f is %.1f m from the source", lat, lon, miss)
+	}
+	if residual > 0.5 {
+		t.Fatalf("residual = %.2f m for exact ranges", residual)
+	}
+
+	cLat, cLon := centroid(alerts)
+	if Distance(cLat, cLon, srcLat, srcLon) < 400 {
+		t.Fatal("centroid unexpectedly close; the case does not test anything")
+	}
+}
+
+func TestMultilaterateNoisyRanges(t *testing.T) {
+	offsets := [][2]float64{{0, 0}, {500, 0}, {0, 500}, {500, 500}, {250, -200}}
+	alerts := sensorsAround(200, 320, []float64{15, -20, 10, -5, 25}, offsets...)
+	srcLat, srcLon := newENU(50.06, 19.94).inverse(200, 320)
+
+	lat, lon, residual, ok := Multilaterate(alerts)
+	if !ok {
+		t.Fatal("not solved")
+	}
+	if miss := Distance(lat, lon, srcLat, srcLon); miss > 30 {
+		t.Fatalf("estimate is %.1f m from the source", miss)
+	}
+	if residual <= 0 || residual > 25 {
+		t.Fatalf("residual = %.2f m", residual)
+	}
+	sg := SourceGroup{Lat: lat, Lon: lon, Alerts: alerts}
+	if u := sg.UncertaintyRadius(); math.Abs(u-math.Max(residual, MinUncertaintyRadius)) > 1 {
+		t.Fatalf("uncertainty %.2f does not match residual %.2f", u, residual)
+	}
+}
+
+func TestMultilaterateNeedsThreeRanges(t *testing.T) {
+	alerts := sensorsAround(100, 100, nil, [2]float64{0, 0}, [2]float64{300, 0}, [2]float64{0, 300})
+	alerts[2].Distance = 0
+
+	if _, _, _, ok := Multilaterate(alerts); ok {
+		t.Fatal("solved with two ranges")
+	}
+	lat, lon, residual := locate(alerts)
+	cLat, cLon := centroid(alerts)
+	if lat != cLat || lon != cLon || residual != 0 {
+		t.Fatalf("fallback = %.6f,%.6f,%.2f, want centroid", lat, lon, residual)
+	}
+}
@@ -0,0 +1,22 @@
+package processor
+
+import "github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+
+// Positions looks up where a sensor is installed (repository.SensorRegistry).
+type Positions interface {
+	Position(deviceID string) (lat, lon float64, ok bool)
+}
+
+// Locate fills in the position of an alert sent without coordinates from the
+// sensor registry. It reports whether the alert was changed.
+func Locate(a *models.Alert, p Positions) bool {
+	if a == nil || p == nil || a.Lat != 0 || a.Lon != 0 {
+		return false
+	}
+	lat, lon, ok := p.Position(a.DeviceID)
+	if !ok || (lat == 0 && lon == 0) {
+		return false
+	}
+	a.Lat, a.Lon = lat, lon
+	return true
+}
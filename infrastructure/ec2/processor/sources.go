@@ -0,0 +1,237 @@
+package processor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+// DefaultMinSharedAlerts is how many alerts a recomputed group must share with a
+// tracked source to be treated as the same source.
+const DefaultMinSharedAlerts = 2
+
+// SourceSet tracks localized sources under stable IDs. Groups recomputed by
+// FindPotentialSources are upserted: a group that shares at least minShared
+// alerts with a tracked source updates it instead of being added again.
+type SourceSet struct {
+	mu        sync.RWMutex
+	sources   []*SourceGroup
+	minShared int
+}
+
+func NewSourceSet(minShared int) *SourceSet {
+	return &SourceSet{minShared: minShared}
+}
+
+// Upsert merges groups into the set and returns copies of the sources that were
+// created and of those whose alerts or position changed.
+func (s *SourceSet) Upsert(groups []SourceGroup, now time.Time) (created, updated []SourceGroup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added, changed := map[*SourceGroup]bool{}, map[*SourceGroup]bool{}
+	for _, g := range groups {
+		existing := s.bestMatch(g)
+		if existing == nil {
+			sg := &SourceGroup{
+				ID:        newSourceID(),
+				Lat:       g.Lat,
+				Lon:       g.Lon,
+				Residual:  g.Residual,
+				Alerts:    append([]*models.Alert{}, g.Alerts...),
+				FirstSeen: now,
+				UpdatedAt: now,
+			}
+			s.sources = append(s.sources, sg)
+			added[sg] = true
+			continue
+		}
+
+		grew := false
+		for _, a := range g.Alerts {
+			if !containsKey(existing.Alerts, a) {
+				existing.Alerts = append(existing.Alerts, a)
+				grew = true
+			}
+		}
+		if grew {
+			// the position must fit all alerts, not only those of g
+			existing.Lat, existing.Lon, existing.Residual = locate(existing.Alerts)
+			existing.UpdatedAt = now
+			changed[existing] = true
+		}
+	}
+
+	// copies are taken last, so a source created and grown in the same batch
+	// is returned once, with all its alerts
+	for _, sg := range s.sources {
+		switch {
+		case added[sg]:
+			created = append(created, sg.copy())
+		case changed[sg]:
+			updated = append(updated, sg.copy())
+		}
+	}
+	return created, updated
+}
+
+// Expire removes sources none of whose alerts were created after cutoff and
+// returns them.
+func (s *SourceSet) Expire(cutoff time.Time) []SourceGroup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.sources[:0]
+	var expired []SourceGroup
+	for _, sg := range s.sources {
+		if sg.lastAlertAt().After(cutoff) {
+			kept = append(kept, sg)
+		} else {
+			expired = append(expired, *sg)
+		}
+	}
+	clear(s.sources[len(kept):])
+	s.sources = kept
+	return expired
+}
+
+// Add inserts already identified sources, e.g. restored from storage.
+func (s *SourceSet) Add(sources ...SourceGroup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sg := range sources {
+		cp := sg.copy()
+		s.sources = append(s.sources, &cp)
+	}
+}
+
+func (s *SourceSet) Get(id string) (SourceGroup, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, sg := range s.sources {
+		if sg.ID == id {
+			return sg.copy(), true
+		}
+	}
+	return SourceGroup{}, false
+}
+
+// List returns deep copies of the sources accepted by keep (all when nil).
+func (s *SourceSet) List(keep func(SourceGroup) bool) []SourceGroup {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make([]SourceGroup, 0, len(s.sources))
+	for _, sg := range s.sources {
+		if keep == nil || keep(*sg) {
+			res = append(res, sg.copy())
+		}
+	}
+	return res
+}
+
+func (s *SourceSet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.sources)
+}
+
+func (s *SourceSet) bestMatch(g SourceGroup) *SourceGroup {
+	var best *SourceGroup
+	bestShared := 0
+	for _, sg := range s.sources {
+		shared := 0
+		for _, a := range g.Alerts {
+			if containsKey(sg.Alerts, a) {
+				shared++
+			}
+		}
+		if shared >= s.minShared && shared > bestShared {
+			best, bestShared = sg, shared
+		}
+	}
+	return best
+}
+
+// copy returns a copy with its own alert values, safe to modify or marshal
+// outside the set's lock.
+func (sg *SourceGroup) copy() SourceGroup {
+	cp := *sg
+	cp.Alerts = make([]*models.Alert, 0, len(sg.Alerts))
+	for _, a := range sg.Alerts {
+		if a == nil {
+			continue
+		}
+		ac := *a
+		cp.Alerts = append(cp.Alerts, &ac)
+	}
+	return cp
+}
+
+func (sg *SourceGroup) lastAlertAt() time.Time {
+	var last time.Time
+	for _, a := range sg.Alerts {
+		if a == nil {
+			continue
+		}
+		if ts, err := time.Parse(time.RFC3339, a.CreatedAt); err == nil && ts.After(last) {
+			last = ts
+		}
+	}
+	return last
+}
+
+func containsKey(alerts []*models.Alert, a *models.Alert) bool {
+	for _, v := range alerts {
+		if v != nil && v.DeviceID == a.DeviceID && v.TS == a.TS {
+			return true
+		}
+	}
+	return false
+}
+
+func newSourceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "src-" + hex.EncodeToString(b)
+}
+
+// Record converts a tracked source to its stored form.
+func (sg SourceGroup) Record(state string) models.Source {
+	rec := models.Source{
+		ID:        sg.ID,
+		Lat:       sg.Lat,
+		Lon:       sg.Lon,
+		Residual:  sg.Residual,
+		AlertKeys: make([]string, 0, len(sg.Alerts)),
+		State:     state,
+		FirstSeen: sg.FirstSeen.UTC().Format(time.RFC3339),
+		UpdatedAt: sg.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	for _, a := range sg.Alerts {
+		if a != nil {
+			rec.AlertKeys = append(rec.AlertKeys, a.Key())
+		}
+	}
+	if last := sg.lastAlertAt(); !last.IsZero() {
+		rec.LastAlertAt = last.UTC().Format(time.RFC3339)
+	}
+	return rec
+}
+
+// SourceFromRecord rebuilds a source from storage with the alerts loaded for
+// its AlertKeys.
+func SourceFromRecord(rec models.Source, alerts []*models.Alert) SourceGroup {
+	sg := SourceGroup{
+		ID:       rec.ID,
+		Lat:      rec.Lat,
+		Lon:      rec.Lon,
+		Residual: rec.Residual,
+		Alerts:   alerts,
+	}
+	sg.FirstSeen, _ = time.Parse(time.RFC3339, rec.FirstSeen)
+	sg.UpdatedAt, _ = time.Parse(time.RFC3339, rec.UpdatedAt)
+	return sg
+}
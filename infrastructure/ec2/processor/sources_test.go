@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+func testAlert(dev, ts string, created time.Time) *models.Alert {
+	return &models.Alert{DeviceID: dev, TS: ts, CreatedAt: created.Format(time.RFC3339)}
+}
+
+func TestSourceSetUpsertKeepsIdentity(t *testing.T) {
+	now := time.Now().UTC()
+	a := testAlert("A", "t1", now)
+	b := testAlert("B", "t1", now)
+	c := testAlert("C", "t1", now)
+	d := testAlert("D", "t2", now)
+
+	set := NewSourceSet(2)
+	created, updated := set.Upsert([]SourceGroup{{Lat: 1, Lon: 1, Alerts: []*models.Alert{a, b, c}}}, now)
+	if len(created) != 1 || len(updated) != 0 || created[0].ID == "" {
+		t.Fatalf("first upsert: created=%v updated=%v", created, updated)
+	}
+	id := created[0].ID
+
+	// same clique recomputed: nothing changes
+	created, updated = set.Upsert([]SourceGroup{{Lat: 1, Lon: 1, Alerts: []*models.Alert{a, b, c}}}, now)
+	if len(created) != 0 || len(updated) != 0 {
+		t.Fatalf("identical upsert: created=%v updated=%v", created, updated)
+	}
+
+	// grown group sharing two alerts updates the same source
+	created, updated = set.Upsert([]SourceGroup{{Lat: 2, Lon: 2, Alerts: []*models.Alert{b, c, d}}}, now)
+	if len(created) != 0 || len(updated) != 1 || updated[0].ID != id {
+		t.Fatalf("overlapping upsert: created=%v updated=%v", created, updated)
+	}
+	got, _ := set.Get(id)
+	if lat, lon, _ := locate(got.Alerts); len(got.Alerts) != 4 || got.Lat != lat || got.Lon != lon {
+		t.Fatalf("merged source = %+v", got)
+	}
+
+	// disjoint group is a new source
+	e := testAlert("E", "t3", now)
+	f := testAlert("F", "t3", now)
+	g := testAlert("G", "t3", now)
+	created, _ = set.Upsert([]SourceGroup{{Alerts: []*models.Alert{e, f, g, a}}}, now)
+	if len(created) != 1 || created[0].ID == id || set.Len() != 2 {
+		t.Fatalf("disjoint upsert: created=%v len=%d", created, set.Len())
+	}
+}
+
+func TestSourceSetUpsertGrownInSameBatch(t *testing.T) {
+	now := time.Now().UTC()
+	a := testAlert("A", "t1", now)
+	b := testAlert("B", "t1", now)
+	c := testAlert("C", "t1", now)
+	d := testAlert("D", "t1", now)
+
+	set := NewSourceSet(2)
+	created, updated := set.Upsert([]SourceGroup{
+		{Alerts: []*models.Alert{a, b, c}},
+		{Alerts: []*models.Alert{b, c, d}},
+	}, now)
+	if len(created) != 1 || len(updated) != 0 || len(created[0].Alerts) != 4 {
+		t.Fatalf("created=%+v updated=%+v", created, updated)
+	}
+}
+
+func TestSourceSetExpire(t *testing.T) {
+	now := time.Now().UTC()
+	old := now.Add(-10 * time.Minute)
+
+	set := NewSourceSet(2)
+	set.Upsert([]SourceGroup{{Alerts: []*models.Alert{testAlert("A", "1", old), testAlert("B", "1", old)}}}, old)
+	set.Upsert([]SourceGroup{{Alerts: []*models.Alert{testAlert("C", "2", now), testAlert("D", "2", now)}}}, now)
+
+	expired := set.Expire(now.Add(-5 * time.Minute))
+	if len(expired) != 1 || expired[0].Alerts[0].DeviceID != "A" || set.Len() != 1 {
+		t.Fatalf("expired=%v len=%d", expired, set.Len())
+	}
+}
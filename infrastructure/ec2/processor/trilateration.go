@@ -2,17 +2,26 @@ package processor
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/metrics"
 	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
 )
 
 type SourceGroup struct {
-	Lat    float64         `json:"lat"`
-	Lon    float64         `json:"lon"`
-	Alerts []*models.Alert `json:"alerts"`
+	ID  string  `json:"id,omitempty"`
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+	// RMS range residual (m) of the multilaterated position, 0 for a centroid
+	Residual  float64         `json:"residual"`
+	Alerts    []*models.Alert `json:"alerts"`
+	FirstSeen time.Time       `json:"firstSeen,omitzero"`
+	UpdatedAt time.Time       `json:"updatedAt,omitzero"`
 }
 
 func FindPotentialSources(alerts []*models.Alert, minOverlaps int) []SourceGroup {
+	defer metrics.Since(metrics.FindSourcesDuration, time.Now())
+
 	n := len(alerts)
 	if n < 3 {
 		return nil
@@ -51,18 +60,20 @@ func FindPotentialSources(alerts []*models.Alert, minOverlaps int) []SourceGroup
 			fmt.Printf("Candidate clique indices: %v\n", r)
 			if validGroup(r, overlap, minOverlaps) {
 				fmt.Println("Valid group!")
-				sumLat, sumLon := 0.0, 0.0
+				metrics.Cliques.WithLabelValues("valid").Inc()
 				groupAlerts := make([]*models.Alert, len(r))
 				for i, idx := range r {
-					sumLat += alerts[idx].Lat
-					sumLon += alerts[idx].Lon
 					groupAlerts[i] = alerts[idx]
 				}
+				lat, lon, residual := locate(groupAlerts)
 				results = append(results, SourceGroup{
-					Lat:    sumLat / float64(len(r)),
-					Lon:    sumLon / float64(len(r)),
-					Alerts: groupAlerts,
+					Lat:      lat,
+					Lon:      lon,
+					Residual: residual,
+					Alerts:   groupAlerts,
 				})
+			} else {
+				metrics.Cliques.WithLabelValues("rejected").Inc()
 			}
 			return
 		}
@@ -105,9 +116,6 @@ func MergeGroups(groups []SourceGroup, minShared int) []SourceGroup {
 			continue
 		}
 		alerts := append([]*models.Alert{}, g.Alerts...)
-		sumLat := g.Lat * float64(len(alerts))
-		sumLon := g.Lon * float64(len(alerts))
-		count := len(alerts)
 		used[i] = true
 
 		for j := i + 1; j < len(groups); j++ {
@@ -120,17 +128,17 @@ func MergeGroups(groups []SourceGroup, minShared int) []SourceGroup {
 						alerts = append(alerts, a)
 					}
 				}
-				sumLat += groups[j].Lat * float64(len(groups[j].Alerts))
-				sumLon += groups[j].Lon * float64(len(groups[j].Alerts))
-				count += len(groups[j].Alerts)
 				used[j] = true
 			}
 		}
 
+		// solved again on all alerts instead of averaging the groups
+		lat, lon, residual := locate(alerts)
 		merged = append(merged, SourceGroup{
-			Lat:    sumLat / float64(count),
-			Lon:    sumLon / float64(count),
-			Alerts: alerts,
+			Lat:      lat,
+			Lon:      lon,
+			Residual: residual,
+			Alerts:   alerts,
 		})
 	}
 
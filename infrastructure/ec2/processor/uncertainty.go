@@ -0,0 +1,26 @@
+package processor
+
+import "math"
+
+// MinUncertaintyRadius is the floor for the reported uncertainty, roughly the
+// accuracy of the sensors' own GPS fixes.
+const MinUncertaintyRadius = 10.0
+
+// UncertaintyRadius estimates how far (in meters) the true source may be from
+// the reported position: the RMS of the range residuals |d(source, sensor) -
+// alert distance| over the contributing alerts.
+func (sg SourceGroup) UncertaintyRadius() float64 {
+	sum, n := 0.0, 0
+	for _, a := range sg.Alerts {
+		if a == nil || a.Distance <= 0 {
+			continue
+		}
+		r := Distance(sg.Lat, sg.Lon, a.Lat, a.Lon) - a.Distance
+		sum += r * r
+		n++
+	}
+	if n == 0 {
+		return MinUncertaintyRadius
+	}
+	return math.Max(MinUncertaintyRadius, math.Sqrt(sum/float64(n)))
+}
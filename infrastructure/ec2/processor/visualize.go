@@ -1,37 +1,60 @@
 package processor
 
 import (
+	"bufio"
 	"fmt"
 	"image/color"
+	"io"
+	"math"
+	"os"
 	"strconv"
 
 	"github.com/fogleman/gg"
 	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
 )
 
+// Visualize renders the map at 1 m = 1 px into a PNG file.
 func Visualize(alerts []*models.Alert, sources []SourceGroup, filename string) error {
-	const margin = 500
-	const pixelsPerMeter = 1.0 // 1 metr = 1 px
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	if err := Render(bw, alerts, sources, 0); err != nil {
+		f.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Render draws alerts and sources as a PNG to w. With maxSize > 0 the map is
+// scaled down so that neither side exceeds maxSize pixels; otherwise 1 m = 1 px.
+func Render(w io.Writer, alerts []*models.Alert, sources []SourceGroup, maxSize int) error {
+	const marginMeters = 500
 
 	if len(alerts) == 0 {
 		return fmt.Errorf("no alerts to visualize")
 	}
 
+	// granice obejmują źródła i całe okręgi detekcji, nie tylko czujniki
 	minLat, maxLat := alerts[0].Lat, alerts[0].Lat
 	minLon, maxLon := alerts[0].Lon, alerts[0].Lon
+	extend := func(lat, lon float64) {
+		minLat, maxLat = math.Min(minLat, lat), math.Max(maxLat, lat)
+		minLon, maxLon = math.Min(minLon, lon), math.Max(maxLon, lon)
+	}
 	for _, a := range alerts {
-		if a.Lat < minLat {
-			minLat = a.Lat
-		}
-		if a.Lat > maxLat {
-			maxLat = a.Lat
-		}
-		if a.Lon < minLon {
-			minLon = a.Lon
-		}
-		if a.Lon > maxLon {
-			maxLon = a.Lon
-		}
+		dLat := a.Distance / earthRadius * 180 / math.Pi
+		dLon := dLat / math.Cos(a.Lat*math.Pi/180)
+		extend(a.Lat-dLat, a.Lon-dLon)
+		extend(a.Lat+dLat, a.Lon+dLon)
+	}
+	for _, s := range sources {
+		extend(s.Lat, s.Lon)
 	}
 
 	lat0 := minLat
@@ -47,8 +70,14 @@ func Visualize(alerts []*models.Alert, sources []SourceGroup, filename string) e
 	widthMeters := lonToMeters(maxLon, maxLat)
 	heightMeters := latToMeters(maxLat)
 
-	imgWidth := int(widthMeters*pixelsPerMeter) + 2*margin
-	imgHeight := int(heightMeters*pixelsPerMeter) + 2*margin
+	pixelsPerMeter := 1.0 // 1 metr = 1 px
+	if longest := math.Max(widthMeters, heightMeters) + 2*marginMeters; maxSize > 0 && longest > float64(maxSize) {
+		pixelsPerMeter = float64(maxSize) / longest
+	}
+	margin := marginMeters * pixelsPerMeter
+
+	imgWidth := int(widthMeters*pixelsPerMeter + 2*margin)
+	imgHeight := int(heightMeters*pixelsPerMeter + 2*margin)
 
 	project := func(lat, lon float64) (float64, float64) {
 		x := margin + lonToMeters(lon, lat)*pixelsPerMeter
@@ -75,9 +104,6 @@ func Visualize(alerts []*models.Alert, sources []SourceGroup, filename string) e
 
 		dc.SetColor(color.Black)
 		dc.DrawStringAnchored(a.DeviceID+" "+strconv.Itoa(int(a.Distance))+" m", x, y-radiusPixels-10, 0.5, 0.5)
-
-		fmt.Printf("Alert: %s at (%.6f, %.6f) radius %.1f m -> pixel (%.1f, %.1f)\n",
-			a.DeviceID, a.Lat, a.Lon, a.Distance, x, y)
 	}
 
 	// Rysowanie linii między wszystkimi alertami
@@ -102,12 +128,9 @@ func Visualize(alerts []*models.Alert, sources []SourceGroup, filename string) e
 	for _, s := range sources {
 		x, y := project(s.Lat, s.Lon)
 		dc.SetColor(color.RGBA{0, 255, 0, 255})
-		dc.DrawCircle(x, y, 50) // stała wielkość na obrazku
+		dc.DrawCircle(x, y, math.Max(6, 50*pixelsPerMeter))
 		dc.Fill()
-
-		fmt.Printf("Source: Lat=%.6f Lon=%.6f composed of %d alerts -> pixel (%.1f, %.1f)\n",
-			s.Lat, s.Lon, len(s.Alerts), x, y)
 	}
 
-	return dc.SavePNG(filename)
+	return dc.EncodePNG(w)
 }
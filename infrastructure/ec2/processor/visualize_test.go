@@ -0,0 +1,56 @@
+package processor
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+func TestRenderMaxSize(t *testing.T) {
+	alerts := []*models.Alert{
+		{DeviceID: "A", Lat: 50.00, Lon: 19.90, Distance: 800},
+		{DeviceID: "B", Lat: 50.03, Lon: 19.95, Distance: 900},
+	}
+	sources := []SourceGroup{{Lat: 50.015, Lon: 19.925, Alerts: alerts}}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, alerts, sources, 800); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("not a PNG: %v", err)
+	}
+	b := img.Bounds()
+	if b.Dx() > 800 || b.Dy() > 800 || max(b.Dx(), b.Dy()) < 790 {
+		t.Fatalf("image %dx%d, want longest side ~800", b.Dx(), b.Dy())
+	}
+}
+
+func TestRenderBoundsCoverSourcesAndRadii(t *testing.T) {
+	alert := &models.Alert{DeviceID: "A", Lat: 50.00, Lon: 20.00, Distance: 1000}
+	source := SourceGroup{Lat: 50.00, Lon: 20.05, Alerts: []*models.Alert{alert}} // ~3.6 km east
+
+	var buf bytes.Buffer
+	if err := Render(&buf, []*models.Alert{alert}, []SourceGroup{source}, 0); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1 m = 1 px: 1 km west of the sensor + ~3.6 km to the source + 2 x 500 m margin
+	widthMeters := Haversine(50, 20, 50, 20.05) + 1000 + 2*500
+	heightMeters := 2*1000 + 2*500.0
+	if b := img.Bounds(); float64(b.Dx()) < widthMeters-5 || float64(b.Dy()) < heightMeters-5 {
+		t.Fatalf("image %dx%d, want at least %.0fx%.0f", b.Dx(), b.Dy(), widthMeters, heightMeters)
+	}
+}
+
+func TestRenderNoAlerts(t *testing.T) {
+	if err := Render(&bytes.Buffer{}, nil, nil, 0); err == nil {
+		t.Fatal("expected error for empty map")
+	}
+}
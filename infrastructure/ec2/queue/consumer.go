@@ -3,66 +3,304 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/metrics"
 	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/resilience"
 )
 
 type HandlerFunc func(ctx context.Context, env models.Envelope) error
 
 type Consumer struct {
-	sqs      *sqs.Client
-	queueURL string
-	handle   HandlerFunc
-	logger   *log.Logger
+	sqs           *sqs.Client
+	queueURL      string
+	quarantineURL string
+	handle        HandlerFunc
+	logger        *log.Logger
+	drainTimeout  time.Duration
+	sqsBreaker    *resilience.Breaker
+	deps          []*resilience.Breaker
+	backoff       *resilience.Backoff
+
+	// activity, read by health checks
+	running       atomic.Bool
+	lastLoop      atomic.Int64 // unix nanos
+	lastProcessed atomic.Int64
+	processed     atomic.Uint64
+	failed        atomic.Uint64
+}
+
+// Activity is a snapshot of what the consumer loop has been doing.
+type Activity struct {
+	Running         bool      `json:"running"`
+	LastLoopAt      time.Time `json:"lastLoopAt,omitzero"`
+	LastProcessedAt time.Time `json:"lastProcessedAt,omitzero"`
+	Processed       uint64    `json:"processed"`
+	Failed          uint64    `json:"failed"`
+}
+
+// Backlog is the approximate number of messages in a queue.
+type Backlog struct {
+	Visible  int `json:"visible"`
+	InFlight int `json:"inFlight"`
+	Delayed  int `json:"delayed"`
+}
+
+func NewConsumer(sqsCli *sqs.Client, queueURL, quarantineURL string, handler HandlerFunc, logger *log.Logger) *Consumer {
+	return &Consumer{
+		sqs:           sqsCli,
+		queueURL:      queueURL,
+		quarantineURL: quarantineURL,
+		handle:        handler,
+		logger:        logger,
+		drainTimeout:  30 * time.Second,
+		backoff:       resilience.NewBackoff(500*time.Millisecond, time.Minute),
+	}
 }
 
-func NewConsumer(sqsCli *sqs.Client, queueURL string, handler HandlerFunc, logger *log.Logger) *Consumer {
-	return &Consumer{sqs: sqsCli, queueURL: queueURL, handle: handler, logger: logger}
+// SetBreakers guards the SQS calls with sqsBreaker and pauses receiving while
+// any of deps is open, so messages are not pulled only to fail and burn through
+// the redrive limit during an outage.
+func (c *Consumer) SetBreakers(sqsBreaker *resilience.Breaker, deps ...*resilience.Breaker) {
+	c.sqsBreaker = sqsBreaker
+	c.deps = deps
 }
 
+// SetDrainTimeout sets how long an in-flight message may keep running after
+// ctx passed to Run is cancelled.
+func (c *Consumer) SetDrainTimeout(d time.Duration) {
+	c.drainTimeout = d
+}
+
+// Run receives and handles messages until ctx is cancelled. Cancelling ctx stops
+// receiving immediately; a message that is already being handled gets up to
+// drainTimeout to finish before its context is cancelled too. Run returns only
+// after the in-flight message is done.
 func (c *Consumer) Run(ctx context.Context) {
 	c.logger.Printf("SQS consumer started; queue=%s", c.queueURL)
+	c.running.Store(true)
+	defer c.running.Store(false)
+
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-workCtx.Done():
+			return
+		}
+		select {
+		case <-time.After(c.drainTimeout):
+			c.logger.Printf("drain timeout (%s) exceeded; cancelling in-flight message", c.drainTimeout)
+			cancelWork()
+		case <-workCtx.Done():
+		}
+	}()
+
 	for {
 		select {
 		case <-ctx.Done():
+			c.logger.Printf("SQS consumer stopped")
 			return
 		default:
 		}
+		c.lastLoop.Store(time.Now().UnixNano())
+
+		if b := c.unavailableDependency(); b != nil {
+			d := c.backoff.Next()
+			c.logger.Printf("%s circuit open; pausing receive for %s", b.Name(), d.Round(time.Millisecond))
+			sleep(ctx, d)
+			continue
+		}
 
-		out, err := c.sqs.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
-			QueueUrl:            &c.queueURL,
-			MaxNumberOfMessages: 1,
-			WaitTimeSeconds:     20,
-			VisibilityTimeout:   60,
+		var out *sqs.ReceiveMessageOutput
+		err := c.sqsBreaker.Do(func() (err error) {
+			out, err = c.sqs.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+				QueueUrl:                    &c.queueURL,
+				MaxNumberOfMessages:         1,
+				WaitTimeSeconds:             20,
+				VisibilityTimeout:           60,
+				MessageSystemAttributeNames: []types.MessageSystemAttributeName{types.MessageSystemAttributeNameMessageGroupId},
+			})
+			return err
 		})
 		if err != nil {
-			c.logger.Printf("receive error: %v", err)
-			time.Sleep(2 * time.Second)
+			if ctx.Err() != nil {
+				continue
+			}
+			d := c.backoff.Next()
+			c.logger.Printf("receive error (attempt %d, retry in %s): %v", c.backoff.Attempt(), d.Round(time.Millisecond), err)
+			sleep(ctx, d)
 			continue
 		}
 		if len(out.Messages) == 0 {
+			c.backoff.Reset()
 			continue
 		}
 
+		metrics.MessagesReceived.Add(float64(len(out.Messages)))
 		for _, m := range out.Messages {
-			var env models.Envelope
-			if err := json.Unmarshal([]byte(*m.Body), &env); err != nil {
-				c.logger.Printf("bad message: %v; body=%s", err, *m.Body)
+			env, reason, err := DecodeEnvelope(aws.ToString(m.Body))
+			if err != nil {
+				metrics.MessagesFailed.WithLabelValues(reason).Inc()
+				c.logger.Printf("bad message (%s): %v; body=%s", reason, err, aws.ToString(m.Body))
+				c.quarantine(workCtx, m, reason, err)
 				continue
 			}
 
-			if err := c.handle(ctx, env); err != nil {
-				c.logger.Printf("handler error: %v", err)
+			if err := c.handle(workCtx, env); err != nil {
+				c.failed.Add(1)
+				metrics.MessagesFailed.WithLabelValues("handler").Inc()
+				d := c.backoff.Next()
+				c.logger.Printf("handler error (attempt %d, retry in %s): %v", c.backoff.Attempt(), d.Round(time.Millisecond), err)
+				sleep(ctx, d)
 				continue
 			}
 
-			_, _ = c.sqs.DeleteMessage(ctx, &sqs.DeleteMessageInput{
-				QueueUrl:      &c.queueURL,
-				ReceiptHandle: m.ReceiptHandle,
-			})
+			c.backoff.Reset()
+			c.delete(workCtx, m)
+			c.processed.Add(1)
+			metrics.MessagesProcessed.Inc()
+			c.lastProcessed.Store(time.Now().UnixNano())
+		}
+	}
+}
+
+// Name identifies the consumer in readiness checks.
+func (c *Consumer) Name() string {
+	return "sqs"
+}
+
+func (c *Consumer) Activity() Activity {
+	return Activity{
+		Running:         c.running.Load(),
+		LastLoopAt:      unixNano(c.lastLoop.Load()),
+		LastProcessedAt: unixNano(c.lastProcessed.Load()),
+		Processed:       c.processed.Load(),
+		Failed:          c.failed.Load(),
+	}
+}
+
+// Backlog reads the approximate message counts of the consumed queue.
+func (c *Consumer) Backlog(ctx context.Context) (Backlog, error) {
+	return GetBacklog(ctx, c.sqs, c.queueURL)
+}
+
+func GetBacklog(ctx context.Context, sqsCli *sqs.Client, url string) (Backlog, error) {
+	out, err := sqsCli.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl: &url,
+		AttributeNames: []types.QueueAttributeName{
+			types.QueueAttributeNameApproximateNumberOfMessages,
+			types.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+			types.QueueAttributeNameApproximateNumberOfMessagesDelayed,
+		},
+	})
+	if err != nil {
+		return Backlog{}, err
+	}
+	attr := func(name types.QueueAttributeName) int {
+		n, _ := strconv.Atoi(out.Attributes[string(name)])
+		return n
+	}
+	return Backlog{
+		Visible:  attr(types.QueueAttributeNameApproximateNumberOfMessages),
+		InFlight: attr(types.QueueAttributeNameApproximateNumberOfMessagesNotVisible),
+		Delayed:  attr(types.QueueAttributeNameApproximateNumberOfMessagesDelayed),
+	}, nil
+}
+
+func unixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
+
+func (c *Consumer) unavailableDependency() *resilience.Breaker {
+	for _, b := range append([]*resilience.Breaker{c.sqsBreaker}, c.deps...) {
+		if !b.Ready() {
+			return b
 		}
 	}
+	return nil
+}
+
+// DecodeEnvelope parses a queue message body. On failure it returns one of the
+// Reason* codes describing why the message is unusable.
+func DecodeEnvelope(body string) (models.Envelope, string, error) {
+	var env models.Envelope
+	if err := json.Unmarshal([]byte(body), &env); err != nil {
+		return env, ReasonInvalidJSON, err
+	}
+	if env.DeviceID == "" || env.TS == "" {
+		return env, ReasonMissingFields, fmt.Errorf("deviceId=%q ts=%q", env.DeviceID, env.TS)
+	}
+	if _, err := time.Parse(time.RFC3339, env.TS); err != nil {
+		return env, ReasonInvalidTS, err
+	}
+	return env, "", nil
+}
+
+// quarantine moves a poison message to the quarantine queue so it does not cycle
+// until the redrive policy kicks in. Without a quarantine queue it is left for
+// the DLQ.
+func (c *Consumer) quarantine(ctx context.Context, m types.Message, reason string, cause error) {
+	if c.quarantineURL == "" {
+		return
+	}
+
+	groupID := m.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)]
+	if groupID == "" {
+		groupID = "quarantine"
+	}
+	err := c.sqsBreaker.Do(func() error {
+		_, err := c.sqs.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:               &c.quarantineURL,
+			MessageBody:            m.Body,
+			MessageGroupId:         aws.String(groupID),
+			MessageDeduplicationId: m.MessageId,
+			MessageAttributes: map[string]types.MessageAttributeValue{
+				reasonAttribute: {DataType: aws.String("String"), StringValue: aws.String(reason)},
+				errorAttribute:  {DataType: aws.String("String"), StringValue: aws.String(cause.Error())},
+				"sourceQueue":   {DataType: aws.String("String"), StringValue: aws.String(c.queueURL)},
+			},
+		})
+		return err
+	})
+	if err != nil {
+		c.logger.Printf("quarantine error: %v", err)
+		return
+	}
+	c.delete(ctx, m)
+	c.logger.Printf("message %s quarantined; reason=%s", aws.ToString(m.MessageId), reason)
+}
+
+func (c *Consumer) delete(ctx context.Context, m types.Message) {
+	err := c.sqsBreaker.Do(func() error {
+		_, err := c.sqs.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      &c.queueURL,
+			ReceiptHandle: m.ReceiptHandle,
+		})
+		return err
+	})
+	if err != nil {
+		c.logger.Printf("delete error: %v", err)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
 }
@@ -0,0 +1,33 @@
+package queue
+
+import "testing"
+
+func TestDecodeEnvelope(t *testing.T) {
+	cases := []struct {
+		name   string
+		body   string
+		reason string
+	}{
+		{"valid", `{"deviceId":"AAA-001","ts":"2025-12-03T20:00:00Z"}`, ""},
+		{"not json", `deviceId=AAA-001`, ReasonInvalidJSON},
+		{"wrong type", `{"deviceId":1,"ts":"2025-12-03T20:00:00Z"}`, ReasonInvalidJSON},
+		{"missing ts", `{"deviceId":"AAA-001"}`, ReasonMissingFields},
+		{"missing device", `{"ts":"2025-12-03T20:00:00Z"}`, ReasonMissingFields},
+		{"bad ts", `{"deviceId":"AAA-001","ts":"yesterday"}`, ReasonInvalidTS},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env, reason, err := DecodeEnvelope(tc.body)
+			if reason != tc.reason {
+				t.Fatalf("reason = %q, want %q (err=%v)", reason, tc.reason, err)
+			}
+			if tc.reason == "" && (err != nil || env.DeviceID != "AAA-001") {
+				t.Fatalf("unexpected result: env=%+v err=%v", env, err)
+			}
+			if tc.reason != "" && err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// Reason codes attached to quarantined messages.
+const (
+	ReasonInvalidJSON   = "INVALID_JSON"
+	ReasonMissingFields = "MISSING_FIELDS"
+	ReasonInvalidTS     = "INVALID_TS"
+
+	reasonAttribute = "reason"
+	errorAttribute  = "error"
+)
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	// ErrMessageUnreachable: the queue holds messages find could not receive.
+	// In a FIFO queue a group with messages in flight (e.g. another admin
+	// call, or a consumer) blocks the rest of the group until they return.
+	ErrMessageUnreachable = errors.New("message not reachable right now, retry later")
+)
+
+type DeadLetter struct {
+	MessageID     string            `json:"messageId"`
+	Body          string            `json:"body"`
+	GroupID       string            `json:"groupId,omitempty"`
+	Reason        string            `json:"reason,omitempty"`
+	Error         string            `json:"error,omitempty"`
+	ReceiveCount  int               `json:"receiveCount"`
+	SentAt        time.Time         `json:"sentAt"`
+	Attributes    map[string]string `json:"attributes,omitempty"`
+	receiptHandle string
+}
+
+// DLQ gives admin access to a dead-letter style queue (the redrive DLQ or the
+// quarantine). Messages are redriven back to targetURL.
+type DLQ struct {
+	sqs       *sqs.Client
+	url       string
+	targetURL string
+}
+
+func NewDLQ(sqsCli *sqs.Client, url, targetURL string) *DLQ {
+	return &DLQ{sqs: sqsCli, url: url, targetURL: targetURL}
+}
+
+func (d *DLQ) URL() string { return d.url }
+
+func (d *DLQ) Backlog(ctx context.Context) (Backlog, error) {
+	return GetBacklog(ctx, d.sqs, d.url)
+}
+
+// List peeks at up to max messages. Messages are hidden while listing so that a
+// single call does not return duplicates, and made visible again afterwards.
+func (d *DLQ) List(ctx context.Context, max int) ([]DeadLetter, error) {
+	if max <= 0 {
+		max = 10
+	}
+	seen := map[string]bool{}
+	var res []DeadLetter
+	var received []string
+	defer func() { d.release(context.WithoutCancel(ctx), received...) }()
+	for len(res) < max {
+		batch, err := d.receive(ctx, int32(min(10, max-len(res))), 5)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		fresh := 0
+		for _, m := range batch {
+			received = append(received, m.receiptHandle)
+			if seen[m.MessageID] {
+				continue
+			}
+			seen[m.MessageID] = true
+			res = append(res, m)
+			fresh++
+		}
+		if fresh == 0 {
+			break
+		}
+	}
+	return res, nil
+}
+
+func (d *DLQ) Get(ctx context.Context, messageID string) (*DeadLetter, error) {
+	m, err := d.find(ctx, messageID, 5)
+	if err != nil {
+		return nil, err
+	}
+	d.release(ctx, m.receiptHandle)
+	return m, nil
+}
+
+// Redrive sends the message back to the target queue and removes it from the DLQ.
+func (d *DLQ) Redrive(ctx context.Context, messageID string) error {
+	if d.targetURL == "" {
+		return errors.New("redrive target queue not configured")
+	}
+	m, err := d.find(ctx, messageID, 30)
+	if err != nil {
+		return err
+	}
+
+	groupID := m.GroupID
+	if groupID == "" {
+		groupID = "redrive"
+	}
+	_, err = d.sqs.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:               &d.targetURL,
+		MessageBody:            aws.String(m.Body),
+		MessageGroupId:         aws.String(groupID),
+		MessageDeduplicationId: aws.String("redrive-" + m.MessageID),
+	})
+	if err != nil {
+		return fmt.Errorf("redrive send: %w", err)
+	}
+	return d.delete(ctx, m.receiptHandle)
+}
+
+// Purge deletes a single message, or the whole queue when messageID is empty.
+func (d *DLQ) Purge(ctx context.Context, messageID string) error {
+	if messageID == "" {
+		_, err := d.sqs.PurgeQueue(ctx, &sqs.PurgeQueueInput{QueueUrl: &d.url})
+		return err
+	}
+	m, err := d.find(ctx, messageID, 30)
+	if err != nil {
+		return err
+	}
+	return d.delete(ctx, m.receiptHandle)
+}
+
+// findEmptyReceives is how many empty receives in a row find accepts before it
+// reports a message as missing; a short poll can come back empty by chance.
+const findEmptyReceives = 3
+
+// find receives messages until it meets messageID. The others it had to look
+// at are made visible again, so the next admin call can find them at once.
+// When the message is not met but the queue has more messages than were seen,
+// it returns ErrMessageUnreachable rather than ErrMessageNotFound.
+func (d *DLQ) find(ctx context.Context, messageID string, visibility int32) (*DeadLetter, error) {
+	seen := map[string]bool{}
+	var skipped []string
+	defer func() { d.release(context.WithoutCancel(ctx), skipped...) }()
+	for empty := 0; empty < findEmptyReceives; {
+		batch, err := d.receive(ctx, 10, visibility)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+		var found *DeadLetter
+		fresh := 0
+		for i := range batch {
+			if batch[i].MessageID == messageID && found == nil {
+				found = &batch[i]
+				continue
+			}
+			skipped = append(skipped, batch[i].receiptHandle)
+			if !seen[batch[i].MessageID] {
+				seen[batch[i].MessageID] = true
+				fresh++
+			}
+		}
+		if found != nil {
+			return found, nil
+		}
+		if fresh == 0 {
+			break
+		}
+	}
+	// counts include the messages hidden by this call, which are all in seen
+	if b, err := d.Backlog(ctx); err == nil && b.Visible+b.InFlight+b.Delayed > len(seen) {
+		return nil, ErrMessageUnreachable
+	}
+	return nil, ErrMessageNotFound
+}
+
+// release makes received messages visible again.
+func (d *DLQ) release(ctx context.Context, receiptHandles ...string) {
+	for _, h := range receiptHandles {
+		_, _ = d.sqs.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+			QueueUrl:          &d.url,
+			ReceiptHandle:     aws.String(h),
+			VisibilityTimeout: 0,
+		})
+	}
+}
+
+func (d *DLQ) receive(ctx context.Context, max, visibility int32) ([]DeadLetter, error) {
+	out, err := d.sqs.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:                    &d.url,
+		MaxNumberOfMessages:         max,
+		WaitTimeSeconds:             1,
+		VisibilityTimeout:           visibility,
+		MessageAttributeNames:       []string{"All"},
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{types.MessageSystemAttributeNameAll},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]DeadLetter, 0, len(out.Messages))
+	for _, m := range out.Messages {
+		dl := DeadLetter{
+			MessageID:     aws.ToString(m.MessageId),
+			Body:          aws.ToString(m.Body),
+			GroupID:       m.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)],
+			Attributes:    map[string]string{},
+			receiptHandle: aws.ToString(m.ReceiptHandle),
+		}
+		if n, err := strconv.Atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)]); err == nil {
+			dl.ReceiveCount = n
+		}
+		if ms, err := strconv.ParseInt(m.Attributes[string(types.MessageSystemAttributeNameSentTimestamp)], 10, 64); err == nil {
+			dl.SentAt = time.UnixMilli(ms).UTC()
+		}
+		for k, v := range m.MessageAttributes {
+			switch k {
+			case reasonAttribute:
+				dl.Reason = aws.ToString(v.StringValue)
+			case errorAttribute:
+				dl.Error = aws.ToString(v.StringValue)
+			default:
+				dl.Attributes[k] = aws.ToString(v.StringValue)
+			}
+		}
+		res = append(res, dl)
+	}
+	return res, nil
+}
+
+func (d *DLQ) delete(ctx context.Context, receiptHandle string) error {
+	_, err := d.sqs.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      &d.url,
+		ReceiptHandle: aws.String(receiptHandle),
+	})
+	return err
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 500
+
+	// upper bound of DynamoDB requests spent on filling one page
+	maxRequestsPerPage = 10
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type AlertQuery struct {
+	From     time.Time
+	To       time.Time
+	DeviceID string
+	Statuses []string
+	Classes  []string
+	BBox     *models.BBox
+	Limit    int
+	Cursor   string
+}
+
+type AlertPage struct {
+	Alerts     []models.Alert
+	NextCursor string
+}
+
+// QueryAlerts returns one page of alerts matching q, newest first. A deviceId
+// turns the request into a key Query on the table; otherwise the byDay index is
+// queried one day partition at a time, newest day first. Filters are applied
+// after DynamoDB's Limit, so a page may come back short while NextCursor is
+// still set.
+func (r *Repo) QueryAlerts(ctx context.Context, q AlertQuery) (AlertPage, error) {
+	q = normalizeQuery(q)
+
+	startKey, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return AlertPage{}, err
+	}
+
+	names := map[string]string{"#ts": "ts"}
+	values := map[string]types.AttributeValue{
+		":from": &types.AttributeValueMemberS{Value: q.From.UTC().Format(time.RFC3339)},
+		":to":   &types.AttributeValueMemberS{Value: q.To.UTC().Format(time.RFC3339)},
+	}
+	filters := alertFilters(q, names, values)
+
+	in := &dynamodb.QueryInput{
+		TableName:                 aws.String(r.alertsTable),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ScanIndexForward:          aws.Bool(false),
+	}
+	if len(filters) > 0 {
+		in.FilterExpression = aws.String(strings.Join(filters, " AND "))
+	}
+
+	// partitions still to read, current first
+	var days []string
+	if q.DeviceID != "" {
+		values[":dev"] = &types.AttributeValueMemberS{Value: q.DeviceID}
+		in.KeyConditionExpression = aws.String("deviceId = :dev AND #ts BETWEEN :from AND :to")
+		days = []string{""}
+	} else {
+		names["#day"] = dayBucketAttr
+		in.IndexName = aws.String(alertsByDayIndex)
+		in.KeyConditionExpression = aws.String("#day = :day AND #ts BETWEEN :from AND :to")
+		days = dayBuckets(q.From, q.To)
+		if startKey != nil {
+			// resume in the cursor's partition; a cursor with only tsDay
+			// points at the start of that partition
+			day, ok := startKey[dayBucketAttr].(*types.AttributeValueMemberS)
+			if !ok {
+				return AlertPage{}, ErrInvalidCursor
+			}
+			for len(days) > 0 && days[0] > day.Value {
+				days = days[1:]
+			}
+			if len(startKey) == 1 {
+				startKey = nil
+			}
+		}
+	}
+
+	var res []models.Alert
+	for i := 0; i < maxRequestsPerPage && len(res) < q.Limit && len(days) > 0; i++ {
+		if days[0] != "" {
+			values[":day"] = &types.AttributeValueMemberS{Value: days[0]}
+		}
+		in.ExclusiveStartKey = startKey
+		in.Limit = aws.Int32(int32(q.Limit - len(res)))
+
+		var out *dynamodb.QueryOutput
+		err = r.breaker.Do(func() (err error) {
+			out, err = r.ddb.Query(ctx, in)
+			return err
+		})
+		if err != nil {
+			return AlertPage{}, err
+		}
+
+		var page []models.Alert
+		if err := attributevalue.UnmarshalListOfMaps(out.Items, &page); err != nil {
+			return AlertPage{}, err
+		}
+		res = append(res, page...)
+
+		startKey = out.LastEvaluatedKey
+		if startKey == nil {
+			days = days[1:]
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool { return res[i].TS > res[j].TS })
+
+	if startKey == nil && len(days) > 0 && days[0] != "" {
+		startKey = map[string]types.AttributeValue{dayBucketAttr: &types.AttributeValueMemberS{Value: days[0]}}
+	}
+	next, err := encodeCursor(startKey)
+	if err != nil {
+		return AlertPage{}, err
+	}
+	return AlertPage{Alerts: res, NextCursor: next}, nil
+}
+
+func normalizeQuery(q AlertQuery) AlertQuery {
+	if q.To.IsZero() {
+		q.To = time.Now().UTC()
+	}
+	if q.From.IsZero() {
+		q.From = q.To.Add(-time.Hour)
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit > MaxPageSize {
+		q.Limit = MaxPageSize
+	}
+	return q
+}
+
+func alertFilters(q AlertQuery, names map[string]string, values map[string]types.AttributeValue) []string {
+	var filters []string
+
+	if len(q.Statuses) > 0 {
+		names["#status"] = "status"
+		var in []string
+		includesNew := false
+		for i, s := range q.Statuses {
+			k := fmt.Sprintf(":st%d", i)
+			values[k] = &types.AttributeValueMemberS{Value: s}
+			in = append(in, k)
+			includesNew = includesNew || s == models.StatusNew
+		}
+		f := "#status IN (" + strings.Join(in, ", ") + ")"
+		if includesNew {
+			f = "(" + f + " OR attribute_not_exists(#status))"
+		}
+		filters = append(filters, f)
+	}
+
+	if len(q.Classes) > 0 {
+		names["#class"] = "class"
+		var in []string
+		for i, c := range q.Classes {
+			k := fmt.Sprintf(":cl%d", i)
+			values[k] = &types.AttributeValueMemberS{Value: c}
+			in = append(in, k)
+		}
+		filters = append(filters, "#class IN ("+strings.Join(in, ", ")+")")
+	}
+
+	if q.BBox != nil {
+		names["#lat"] = "lat"
+		names["#lon"] = "lon"
+		num := func(f float64) types.AttributeValue {
+			return &types.AttributeValueMemberN{Value: strconv.FormatFloat(f, 'f', -1, 64)}
+		}
+		values[":minLat"] = num(q.BBox.MinLat)
+		values[":maxLat"] = num(q.BBox.MaxLat)
+		values[":minLon"] = num(q.BBox.MinLon)
+		values[":maxLon"] = num(q.BBox.MaxLon)
+		filters = append(filters, "#lat BETWEEN :minLat AND :maxLat", "#lon BETWEEN :minLon AND :maxLon")
+	}
+
+	return filters
+}
+
+// Cursors are the LastEvaluatedKey (string attributes only) as base64url JSON.
+func encodeCursor(key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+	plain := make(map[string]string, len(key))
+	for k, v := range key {
+		s, ok := v.(*types.AttributeValueMemberS)
+		if !ok {
+			return "", fmt.Errorf("unsupported key attribute %q", k)
+		}
+		plain[k] = s.Value
+	}
+	b, err := json.Marshal(plain)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var plain map[string]string
+	if err := json.Unmarshal(b, &plain); err != nil || len(plain) == 0 {
+		return nil, ErrInvalidCursor
+	}
+	key := make(map[string]types.AttributeValue, len(plain))
+	for k, v := range plain {
+		key[k] = &types.AttributeValueMemberS{Value: v}
+	}
+	return key, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	key := map[string]types.AttributeValue{
+		"deviceId": &types.AttributeValueMemberS{Value: "AAA-001"},
+		"ts":       &types.AttributeValueMemberS{Value: "2025-12-03T20:00:00Z"},
+	}
+	cur, err := encodeCursor(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeCursor(cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["deviceId"].(*types.AttributeValueMemberS).Value != "AAA-001" ||
+		got["ts"].(*types.AttributeValueMemberS).Value != "2025-12-03T20:00:00Z" {
+		t.Fatalf("round trip mismatch: %#v", got)
+	}
+
+	if cur, _ := encodeCursor(nil); cur != "" {
+		t.Fatalf("empty key encoded as %q", cur)
+	}
+	for _, bad := range []string{"%%%", "bm90IGpzb24", "e30"} {
+		if _, err := decodeCursor(bad); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeCursor(%q) err = %v", bad, err)
+		}
+	}
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	q := normalizeQuery(AlertQuery{Limit: 10_000})
+	if q.Limit != MaxPageSize {
+		t.Fatalf("limit = %d", q.Limit)
+	}
+	if got := q.To.Sub(q.From); got != time.Hour {
+		t.Fatalf("default window = %s", got)
+	}
+	if q := normalizeQuery(AlertQuery{}); q.Limit != DefaultPageSize {
+		t.Fatalf("default limit = %d", q.Limit)
+	}
+}
+
+func TestDayBuckets(t *testing.T) {
+	from := time.Date(2025, 12, 1, 23, 30, 0, 0, time.UTC)
+	to := time.Date(2025, 12, 3, 0, 10, 0, 0, time.UTC)
+	got := dayBuckets(from, to)
+	want := []string{"2025-12-03", "2025-12-02", "2025-12-01"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("dayBuckets = %v, want %v", got, want)
+	}
+	if got := dayBuckets(to.Add(-time.Minute), to); !slices.Equal(got, []string{"2025-12-03"}) {
+		t.Fatalf("single day = %v", got)
+	}
+	for ts, want := range map[string]string{
+		"2025-12-03T20:00:00Z":      "2025-12-03",
+		"2025-12-03T01:00:00+02:00": "2025-12-02",
+		"2025-12-03":                "",
+	} {
+		if got := DayBucket(ts); got != want {
+			t.Fatalf("DayBucket(%q) = %q, want %q", ts, got, want)
+		}
+	}
+}
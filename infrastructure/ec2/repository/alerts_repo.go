@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"errors"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -10,26 +12,34 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/resilience"
 )
 
 type Repo struct {
 	ddb          *dynamodb.Client
+	breaker      *resilience.Breaker
 	alertsTable  string
 	sensorsTable string
 }
 
-func NewRepo(ddb *dynamodb.Client, alertsTable, sensorsTable string) *Repo {
-	return &Repo{ddb: ddb, alertsTable: alertsTable, sensorsTable: sensorsTable}
+// NewRepo creates a DynamoDB-backed repository. Every call goes through breaker,
+// which may be nil.
+func NewRepo(ddb *dynamodb.Client, breaker *resilience.Breaker, alertsTable, sensorsTable string) *Repo {
+	return &Repo{ddb: ddb, breaker: breaker, alertsTable: alertsTable, sensorsTable: sensorsTable}
 }
 
 func (r *Repo) GeAlertByPK(ctx context.Context, deviceID, ts string, consistent bool) (*models.Alert, error) {
-	out, err := r.ddb.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: &r.alertsTable,
-		Key: map[string]types.AttributeValue{
-			"deviceId": &types.AttributeValueMemberS{Value: deviceID},
-			"ts":       &types.AttributeValueMemberS{Value: ts},
-		},
-		ConsistentRead: &consistent,
+	var out *dynamodb.GetItemOutput
+	err := r.breaker.Do(func() (err error) {
+		out, err = r.ddb.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: &r.alertsTable,
+			Key: map[string]types.AttributeValue{
+				"deviceId": &types.AttributeValueMemberS{Value: deviceID},
+				"ts":       &types.AttributeValueMemberS{Value: ts},
+			},
+			ConsistentRead: &consistent,
+		})
+		return err
 	})
 	if err != nil {
 		return nil, err
@@ -48,37 +58,142 @@ func (r *Repo) GeAlertByPK(ctx context.Context, deviceID, ts string, consistent
 	return &a, nil
 }
 
+// GetAlertsLastHour queries the byDay index for the last hour (one or two
+// day partitions).
 func (r *Repo) GetAlertsLastHour(ctx context.Context) ([]models.Alert, error) {
-	now := time.Now().UTC()
-	from := now.Add(-1 * time.Hour).Format(time.RFC3339)
-	to := now.Format(time.RFC3339)
+	to := time.Now().UTC()
+	from := to.Add(-1 * time.Hour)
 
-	input := &dynamodb.ScanInput{
-		TableName:        aws.String(r.alertsTable),
-		FilterExpression: aws.String("#ts BETWEEN :from AND :to"),
-		ExpressionAttributeNames: map[string]string{
-			"#ts": "ts",
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":from": &types.AttributeValueMemberS{Value: from},
-			":to":   &types.AttributeValueMemberS{Value: to},
-		},
+	var all []models.Alert
+	for _, day := range dayBuckets(from, to) {
+		p := dynamodb.NewQueryPaginator(r.ddb, &dynamodb.QueryInput{
+			TableName:              aws.String(r.alertsTable),
+			IndexName:              aws.String(alertsByDayIndex),
+			KeyConditionExpression: aws.String("#day = :day AND #ts BETWEEN :from AND :to"),
+			ExpressionAttributeNames: map[string]string{
+				"#day": dayBucketAttr,
+				"#ts":  "ts",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":day":  &types.AttributeValueMemberS{Value: day},
+				":from": &types.AttributeValueMemberS{Value: from.Format(time.RFC3339)},
+				":to":   &types.AttributeValueMemberS{Value: to.Format(time.RFC3339)},
+			},
+		})
+		for p.HasMorePages() {
+			var page *dynamodb.QueryOutput
+			err := r.breaker.Do(func() (err error) {
+				page, err = p.NextPage(ctx)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			var pageAlerts []models.Alert
+			if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageAlerts); err != nil {
+				return nil, err
+			}
+			all = append(all, pageAlerts...)
+		}
 	}
 
-	var all []models.Alert
-	p := dynamodb.NewScanPaginator(r.ddb, input)
+	return all, nil
+}
 
-	for p.HasMorePages() {
-		page, err := p.NextPage(ctx)
-		if err != nil {
-			return nil, err
-		}
-		var pageAlerts []models.Alert
-		if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageAlerts); err != nil {
-			return nil, err
+var ErrStatusConflict = errors.New("alert status changed concurrently")
+
+// SetAlertRetention sets the alert's TTL (removing it when 0) and source ID.
+func (r *Repo) SetAlertRetention(ctx context.Context, deviceID, ts, sourceID string, ttl int64) error {
+	var set []string
+	values := map[string]types.AttributeValue{}
+	if ttl > 0 {
+		set = append(set, "#ttl = :ttl")
+		values[":ttl"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(ttl, 10)}
+	}
+	if sourceID != "" {
+		set = append(set, "sourceId = :src")
+		values[":src"] = &types.AttributeValueMemberS{Value: sourceID}
+	}
+	var update string
+	if len(set) > 0 {
+		update = "SET " + strings.Join(set, ", ")
+	}
+	if ttl == 0 {
+		update = strings.TrimSpace(update + " REMOVE #ttl")
+	}
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.alertsTable),
+		Key:       alertKey(deviceID, ts),
+		// an update on a missing key would create it (e.g. after TTL deleted it)
+		ConditionExpression:      aws.String("attribute_exists(deviceId)"),
+		UpdateExpression:         aws.String(update),
+		ExpressionAttributeNames: map[string]string{"#ttl": "ttl"},
+	}
+	if len(values) > 0 {
+		input.ExpressionAttributeValues = values
+	}
+	err := r.breaker.Do(func() error {
+		_, err := r.ddb.UpdateItem(ctx, input)
+		return err
+	})
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return nil
+	}
+	return err
+}
+
+// UpdateAlertStatus moves an alert from status `from` to change.To and appends
+// change to its history. The write is conditional on the stored status still
+// being `from`, so concurrent operators cannot skip a transition.
+func (r *Repo) UpdateAlertStatus(ctx context.Context, deviceID, ts, from string, change models.StatusChange) (*models.Alert, error) {
+	entry, err := attributevalue.MarshalMap(change)
+	if err != nil {
+		return nil, err
+	}
+
+	cond := "attribute_exists(deviceId) AND #status = :from"
+	if from == "" || from == models.StatusNew {
+		cond = "attribute_exists(deviceId) AND (attribute_not_exists(#status) OR #status = :from)"
+		from = models.StatusNew
+	}
+
+	var out *dynamodb.UpdateItemOutput
+	err = r.breaker.Do(func() (err error) {
+		out, err = r.ddb.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(r.alertsTable),
+			Key: map[string]types.AttributeValue{
+				"deviceId": &types.AttributeValueMemberS{Value: deviceID},
+				"ts":       &types.AttributeValueMemberS{Value: ts},
+			},
+			ConditionExpression: aws.String(cond),
+			UpdateExpression:    aws.String("SET #status = :to, #history = list_append(if_not_exists(#history, :empty), :change), updatedAt = :at"),
+			ExpressionAttributeNames: map[string]string{
+				"#status":  "status",
+				"#history": "history",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":from":   &types.AttributeValueMemberS{Value: from},
+				":to":     &types.AttributeValueMemberS{Value: change.To},
+				":at":     &types.AttributeValueMemberS{Value: change.At},
+				":empty":  &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+				":change": &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberM{Value: entry}}},
+			},
+			ReturnValues: types.ReturnValueAllNew,
+		})
+		return err
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return nil, ErrStatusConflict
 		}
-		all = append(all, pageAlerts...)
+		return nil, err
 	}
 
-	return all, nil
+	var a models.Alert
+	if err := attributevalue.UnmarshalMap(out.Attributes, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
 }
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/resilience"
+)
+
+var (
+	ErrAudioNotFound = errors.New("audio object not found")
+	ErrInvalidRange  = errors.New("requested range not satisfiable")
+)
+
+// InvalidRangeError is returned by Open for a range outside the object. Size is
+// the object size for the 416 Content-Range, or -1 when it could not be read.
+type InvalidRangeError struct {
+	Size int64
+}
+
+func (e *InvalidRangeError) Error() string { return ErrInvalidRange.Error() }
+func (e *InvalidRangeError) Unwrap() error { return ErrInvalidRange }
+
+// AudioRepo gives access to the audio clips stored in S3.
+type AudioRepo struct {
+	s3      *s3.Client
+	bucket  string
+	breaker *resilience.Breaker
+}
+
+func NewAudioRepo(s3Cli *s3.Client, breaker *resilience.Breaker, bucket string) *AudioRepo {
+	return &AudioRepo{
+		s3:      s3Cli,
+		bucket:  bucket,
+		breaker: breaker,
+	}
+}
+
+func (r *AudioRepo) Enabled() bool {
+	return r != nil && r.bucket != ""
+}
+
+// AudioObject is an open S3 object. Body must be closed by the caller.
+type AudioObject struct {
+	Body          io.ReadCloser
+	ContentType   string
+	ContentLength int64
+	// set only for partial (range) responses, e.g. "bytes 0-1023/48211"
+	ContentRange string
+	ETag         string
+	LastModified time.Time
+}
+
+// Open starts streaming key from S3. byteRange is an HTTP Range header value
+// ("bytes=0-1023") passed through to S3, or empty for the whole object.
+func (r *AudioRepo) Open(ctx context.Context, key, byteRange string) (*AudioObject, error) {
+	in := &s3.GetObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(key),
+	}
+	if byteRange != "" {
+		in.Range = aws.String(byteRange)
+	}
+
+	var out *s3.GetObjectOutput
+	err := r.breaker.Do(func() (err error) {
+		out, err = r.s3.GetObject(ctx, in)
+		return err
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			switch apiErr.ErrorCode() {
+			case "NoSuchKey", "NotFound":
+				return nil, ErrAudioNotFound
+			case "InvalidRange":
+				return nil, &InvalidRangeError{Size: r.size(ctx, key)}
+			}
+		}
+		return nil, err
+	}
+
+	return &AudioObject{
+		Body:          out.Body,
+		ContentType:   aws.ToString(out.ContentType),
+		ContentLength: aws.ToInt64(out.ContentLength),
+		ContentRange:  aws.ToString(out.ContentRange),
+		ETag:          aws.ToString(out.ETag),
+		LastModified:  aws.ToTime(out.LastModified),
+	}, nil
+}
+
+// size returns the object size, or -1 when HeadObject fails.
+func (r *AudioRepo) size(ctx context.Context, key string) int64 {
+	var out *s3.HeadObjectOutput
+	err := r.breaker.Do(func() (err error) {
+		out, err = r.s3.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(r.bucket),
+			Key:    aws.String(key),
+		})
+		return err
+	})
+	if err != nil || out.ContentLength == nil {
+		return -1
+	}
+	return *out.ContentLength
+}
+
+type MediaObject struct {
+	Key          string    `json:"key"`
+	Kind         string    `json:"kind"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// ListDerived lists media derived from an audio clip (spectrograms, denoised
+// audio, ...). They are stored next to the clip with the same base name, e.g.
+// dev/2025-12-03/2025-12-03T20-00-00.spectrogram.png for
+// dev/2025-12-03/2025-12-03T20-00-00.wav.
+func (r *AudioRepo) ListDerived(ctx context.Context, audioKey string) ([]MediaObject, error) {
+	base := strings.TrimSuffix(audioKey, path.Ext(audioKey))
+	prefix := base + "."
+
+	var res []MediaObject
+	p := s3.NewListObjectsV2Paginator(r.s3, &s3.ListObjectsV2Input{
+		Bucket: aws.String(r.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for p.HasMorePages() {
+		var page *s3.ListObjectsV2Output
+		err := r.breaker.Do(func() (err error) {
+			page, err = p.NextPage(ctx)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range page.Contents {
+			key := aws.ToString(o.Key)
+			if key == audioKey {
+				continue
+			}
+			res = append(res, MediaObject{
+				Key:          key,
+				Kind:         strings.TrimPrefix(key, prefix),
+				Size:         aws.ToInt64(o.Size),
+				LastModified: aws.ToTime(o.LastModified),
+			})
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,432 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Kinds of records queued for upload in the outbox.
+const (
+	OutboxAlert  = "alert"
+	OutboxSensor = "sensor"
+	OutboxSource = "source"
+)
+
+var (
+	bucketAlerts   = []byte("alerts")
+	bucketAlertsTS = []byte("alertsByTS") // ts#deviceId -> nil
+	bucketSensors  = []byte("sensors")
+	bucketSources  = []byte("sources")
+	bucketOutbox   = []byte("outbox") // kind/id -> sequence of the last change
+)
+
+// BoltStore keeps alerts, sensors and sources in a local bbolt file, for the
+// edge gateway mode. It implements AlertStore, SensorStore and SourceStore with
+// the same semantics as the DynamoDB tables. Every write also marks the record
+// in an outbox, from which edge.Syncer uploads it once there is connectivity.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens (or creates) the database file at path.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{bucketAlerts, bucketAlertsTS, bucketSensors, bucketSources, bucketOutbox} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// DB gives other edge components (the local queue) their own buckets in the
+// same file.
+func (b *BoltStore) DB() *bolt.DB {
+	return b.db
+}
+
+func (b *BoltStore) Close() error {
+	return b.db.Close()
+}
+
+func (b *BoltStore) Ping(_ context.Context) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketAlerts) == nil {
+			return errors.New("alerts bucket missing")
+		}
+		return nil
+	})
+}
+
+// PutAlert inserts or replaces an alert.
+func (b *BoltStore) PutAlert(a models.Alert) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return putAlert(tx, copyAlert(a))
+	})
+}
+
+// PutSensor inserts or replaces a sensor.
+func (b *BoltStore) PutSensor(s models.Sensor) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx, bucketSensors, OutboxSensor, s.DeviceID, copySensor(s))
+	})
+}
+
+// StoreSynced overwrites a local alert with the copy that won a sync conflict,
+// without queueing it for upload again.
+func (b *BoltStore) StoreSynced(a models.Alert) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		raw, err := json.Marshal(copyAlert(a))
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(bucketAlerts).Put([]byte(a.Key()), raw)
+	})
+}
+
+// RecordAlert stores a newly received alert and moves the sensor's lastSeen
+// forward. A sensor heard of for the first time is registered at the alert's
+// position (there is no lambda-register at the edge).
+func (b *BoltStore) RecordAlert(a models.Alert, at time.Time) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := putAlert(tx, copyAlert(a)); err != nil {
+			return err
+		}
+		var s models.Sensor
+		found, err := getJSON(tx, bucketSensors, a.DeviceID, &s)
+		if err != nil {
+			return err
+		}
+		if !found {
+			s = models.Sensor{DeviceID: a.DeviceID, FirstSeen: at, Lat: a.Lat, Lon: a.Lon}
+		}
+		if at.After(s.LastSeen) {
+			s.LastSeen = at
+		}
+		return putJSON(tx, bucketSensors, OutboxSensor, s.DeviceID, s)
+	})
+}
+
+// RecordHeartbeat moves lastHeartbeat forward and stores the latest telemetry.
+// It reports false for an unknown sensor.
+func (b *BoltStore) RecordHeartbeat(deviceID string, at time.Time, tel *models.Telemetry) (bool, error) {
+	var found bool
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		var s models.Sensor
+		var err error
+		if found, err = getJSON(tx, bucketSensors, deviceID, &s); err != nil || !found {
+			return err
+		}
+		if !at.After(s.LastHeartbeat) {
+			return nil
+		}
+		s.LastHeartbeat = at
+		if tel != nil {
+			s.Telemetry = tel
+		}
+		return putJSON(tx, bucketSensors, OutboxSensor, deviceID, s)
+	})
+	return found, err
+}
+
+func (b *BoltStore) GeAlertByPK(_ context.Context, deviceID, ts string, _ bool) (*models.Alert, error) {
+	var a models.Alert
+	var found bool
+	err := b.db.View(func(tx *bolt.Tx) (err error) {
+		found, err = getJSON(tx, bucketAlerts, deviceID+"#"+ts, &a)
+		return err
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (b *BoltStore) GetAlertsLastHour(_ context.Context) ([]models.Alert, error) {
+	now := time.Now().UTC()
+	var res []models.Alert
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return alertsBetween(tx, now.Add(-time.Hour), now, func(a models.Alert) {
+			res = append(res, a)
+		})
+	})
+	return res, err
+}
+
+func (b *BoltStore) GetAlertsByKeys(_ context.Context, keys []string) ([]models.Alert, error) {
+	var res []models.Alert
+	err := b.db.View(func(tx *bolt.Tx) error {
+		for _, k := range keys {
+			var a models.Alert
+			found, err := getJSON(tx, bucketAlerts, k, &a)
+			if err != nil {
+				return err
+			}
+			if found {
+				res = append(res, a)
+			}
+		}
+		return nil
+	})
+	return res, err
+}
+
+func (b *BoltStore) QueryAlerts(_ context.Context, q AlertQuery) (AlertPage, error) {
+	q = normalizeQuery(q)
+	startKey, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return AlertPage{}, err
+	}
+
+	var matches []models.Alert
+	err = b.db.View(func(tx *bolt.Tx) error {
+		return alertsBetween(tx, q.From, q.To, func(a models.Alert) {
+			if matchesQuery(a, q) {
+				matches = append(matches, a)
+			}
+		})
+	})
+	if err != nil {
+		return AlertPage{}, err
+	}
+	return pageAlerts(matches, q, startKey)
+}
+
+func (b *BoltStore) UpdateAlertStatus(_ context.Context, deviceID, ts, from string, change models.StatusChange) (*models.Alert, error) {
+	var a models.Alert
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		found, err := getJSON(tx, bucketAlerts, deviceID+"#"+ts, &a)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrStatusConflict
+		}
+		if err := applyStatusChange(&a, from, change); err != nil {
+			return err
+		}
+		return putAlert(tx, a)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (b *BoltStore) SetAlertRetention(_ context.Context, deviceID, ts, sourceID string, ttl int64) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		var a models.Alert
+		found, err := getJSON(tx, bucketAlerts, deviceID+"#"+ts, &a)
+		if err != nil || !found {
+			return err
+		}
+		setRetention(&a, sourceID, ttl)
+		return putAlert(tx, a)
+	})
+}
+
+func (b *BoltStore) GetAllSensors(_ context.Context) ([]models.Sensor, error) {
+	var res []models.Sensor
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSensors).ForEach(func(_, v []byte) error {
+			var s models.Sensor
+			if err := json.Unmarshal(v, &s); err != nil {
+				return err
+			}
+			res = append(res, s)
+			return nil
+		})
+	})
+	return res, err
+}
+
+// GetSensor returns one sensor, nil if unknown.
+func (b *BoltStore) GetSensor(_ context.Context, deviceID string) (*models.Sensor, error) {
+	var s models.Sensor
+	var found bool
+	err := b.db.View(func(tx *bolt.Tx) (err error) {
+		found, err = getJSON(tx, bucketSensors, deviceID, &s)
+		return err
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (b *BoltStore) SaveSource(_ context.Context, s models.Source) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx, bucketSources, OutboxSource, s.ID, s)
+	})
+}
+
+func (b *BoltStore) GetSource(_ context.Context, id string) (*models.Source, error) {
+	var s models.Source
+	var found bool
+	err := b.db.View(func(tx *bolt.Tx) (err error) {
+		found, err = getJSON(tx, bucketSources, id, &s)
+		return err
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (b *BoltStore) ListSources(_ context.Context, state string, from, to time.Time) ([]models.Source, error) {
+	lo, hi := from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339)
+	var res []models.Source
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSources).ForEach(func(_, v []byte) error {
+			var s models.Source
+			if err := json.Unmarshal(v, &s); err != nil {
+				return err
+			}
+			if s.State == state && s.UpdatedAt >= lo && s.UpdatedAt <= hi {
+				res = append(res, s)
+			}
+			return nil
+		})
+	})
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].UpdatedAt != res[j].UpdatedAt {
+			return res[i].UpdatedAt > res[j].UpdatedAt
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res, err
+}
+
+func (b *BoltStore) ExpireSource(_ context.Context, id string, at time.Time) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		var s models.Source
+		found, err := getJSON(tx, bucketSources, id, &s)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrSourceNotFound
+		}
+		ts := at.UTC().Format(time.RFC3339)
+		s.State = models.SourceExpired
+		s.ExpiredAt = ts
+		s.UpdatedAt = ts
+		return putJSON(tx, bucketSources, OutboxSource, id, s)
+	})
+}
+
+// OutboxEntry is a record changed locally and not uploaded yet.
+type OutboxEntry struct {
+	Kind string
+	ID   string
+	seq  uint64
+}
+
+// Pending returns up to max outbox entries of kind, oldest key first.
+func (b *BoltStore) Pending(kind string, max int) ([]OutboxEntry, error) {
+	var res []OutboxEntry
+	prefix := []byte(kind + "/")
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketOutbox).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix) && len(res) < max; k, v = c.Next() {
+			res = append(res, OutboxEntry{Kind: kind, ID: string(k[len(prefix):]), seq: binary.BigEndian.Uint64(v)})
+		}
+		return nil
+	})
+	return res, err
+}
+
+// PendingCount is the number of records waiting for upload.
+func (b *BoltStore) PendingCount() int {
+	n := 0
+	b.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(bucketOutbox).Stats().KeyN
+		return nil
+	})
+	return n
+}
+
+// Ack removes an uploaded entry from the outbox, unless the record was changed
+// again after Pending returned it; then it stays for the next round.
+func (b *BoltStore) Ack(e OutboxEntry) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		ob := tx.Bucket(bucketOutbox)
+		key := []byte(e.Kind + "/" + e.ID)
+		if v := ob.Get(key); v != nil && binary.BigEndian.Uint64(v) == e.seq {
+			return ob.Delete(key)
+		}
+		return nil
+	})
+}
+
+func putAlert(tx *bolt.Tx, a models.Alert) error {
+	if err := putJSON(tx, bucketAlerts, OutboxAlert, a.Key(), a); err != nil {
+		return err
+	}
+	return tx.Bucket(bucketAlertsTS).Put([]byte(a.TS+"#"+a.DeviceID), nil)
+}
+
+// alertsBetween calls fn for every alert with ts in [from, to], oldest first.
+func alertsBetween(tx *bolt.Tx, from, to time.Time, fn func(models.Alert)) error {
+	lo, hi := from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339)
+	c := tx.Bucket(bucketAlertsTS).Cursor()
+	for k, _ := c.Seek([]byte(lo)); k != nil; k, _ = c.Next() {
+		ts, dev, _ := strings.Cut(string(k), "#")
+		if ts > hi {
+			break
+		}
+		var a models.Alert
+		found, err := getJSON(tx, bucketAlerts, dev+"#"+ts, &a)
+		if err != nil {
+			return err
+		}
+		if found {
+			fn(a)
+		}
+	}
+	return nil
+}
+
+// putJSON stores v under id and marks it in the outbox.
+func putJSON(tx *bolt.Tx, bucket []byte, kind, id string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucket).Put([]byte(id), raw); err != nil {
+		return err
+	}
+	ob := tx.Bucket(bucketOutbox)
+	seq, err := ob.NextSequence()
+	if err != nil {
+		return err
+	}
+	return ob.Put([]byte(kind+"/"+id), binary.BigEndian.AppendUint64(nil, seq))
+}
+
+func getJSON(tx *bolt.Tx, bucket []byte, id string, v any) (bool, error) {
+	raw := tx.Bucket(bucket).Get([]byte(id))
+	if raw == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+func openTestBolt(t *testing.T) *BoltStore {
+	t.Helper()
+	st, err := OpenBoltStore(filepath.Join(t.TempDir(), "edge.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+func TestBoltStoreOutbox(t *testing.T) {
+	ctx := context.Background()
+	st := openTestBolt(t)
+	now := time.Date(2025, 12, 3, 20, 0, 0, 0, time.UTC)
+
+	a := models.Alert{DeviceID: "AAA-001", TS: now.Format(time.RFC3339), Lat: 50.06, Lon: 19.94, Status: models.StatusNew}
+	if err := st.RecordAlert(a, now); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := st.GetSensor(ctx, "AAA-001"); s == nil || s.Lat != 50.06 || !s.LastSeen.Equal(now) {
+		t.Fatalf("auto-registered sensor = %+v", s)
+	}
+	if n := st.PendingCount(); n != 2 {
+		t.Fatalf("pending = %d, want alert and sensor", n)
+	}
+
+	pending, err := st.Pending(OutboxAlert, 10)
+	if err != nil || len(pending) != 1 || pending[0].ID != a.Key() {
+		t.Fatalf("pending alerts = %+v, %v", pending, err)
+	}
+
+	// changed again before the upload finished: the ack must not drop it
+	change := models.StatusChange{From: models.StatusNew, To: models.StatusAcknowledged, At: now.Add(time.Minute).Format(time.RFC3339)}
+	if _, err := st.UpdateAlertStatus(ctx, a.DeviceID, a.TS, models.StatusNew, change); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Ack(pending[0]); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := st.Pending(OutboxAlert, 10)
+	if len(again) != 1 {
+		t.Fatalf("re-marked alert dropped from outbox: %+v", again)
+	}
+	if err := st.Ack(again[0]); err != nil {
+		t.Fatal(err)
+	}
+	if left, _ := st.Pending(OutboxAlert, 10); len(left) != 0 {
+		t.Fatalf("pending after ack = %+v", left)
+	}
+
+	if ok, err := st.RecordHeartbeat("BBB-002", now, nil); ok || err != nil {
+		t.Fatalf("heartbeat of unknown sensor: %v, %v", ok, err)
+	}
+	if ok, _ := st.RecordHeartbeat("AAA-001", now, &models.Telemetry{}); !ok {
+		t.Fatal("heartbeat of known sensor rejected")
+	}
+}
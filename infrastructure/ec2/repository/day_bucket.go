@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"golang.org/x/sync/errgroup"
+)
+
+// Time-range reads on the alerts table go through the byDay index
+// (hash tsDay = the UTC date of ts, range ts) instead of scanning the table.
+// lambda-alert writes tsDay; older items get it from cmd/backfill.
+const (
+	alertsByDayIndex = "byDay"
+	dayBucketAttr    = "tsDay"
+)
+
+// DayBucket returns the tsDay partition of an alert timestamp: its UTC date
+// ("2025-12-02" for "2025-12-03T01:00:00+02:00"), or "" when ts is not RFC3339.
+func DayBucket(ts string) string {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format(time.DateOnly)
+}
+
+// dayBuckets lists the tsDay partitions covering [from, to], newest first.
+func dayBuckets(from, to time.Time) []string {
+	from, to = from.UTC().Truncate(24*time.Hour), to.UTC()
+	var days []string
+	for d := to.Truncate(24 * time.Hour); !d.Before(from); d = d.Add(-24 * time.Hour) {
+		days = append(days, d.Format(time.DateOnly))
+	}
+	return days
+}
+
+// BackfillStats is the progress of BackfillDayBuckets.
+type BackfillStats struct {
+	Scanned int64
+	Updated int64
+	Skipped int64 // already had tsDay, were changed concurrently or have an unparseable ts
+}
+
+// BackfillDayBuckets sets tsDay on alerts written before the byDay index
+// existed. The table is scanned in parallel segments; each update is
+// conditional, so running it again (or alongside lambda-alert) is safe. With
+// dryRun nothing is written. progress, if not nil, is called after every page.
+func (r *Repo) BackfillDayBuckets(ctx context.Context, segments int, dryRun bool, progress func(BackfillStats)) (BackfillStats, error) {
+	if segments < 1 {
+		segments = 1
+	}
+	var scanned, updated, skipped atomic.Int64
+	snapshot := func() BackfillStats {
+		return BackfillStats{Scanned: scanned.Load(), Updated: updated.Load(), Skipped: skipped.Load()}
+	}
+	var progressMu sync.Mutex
+
+	g, ctx := errgroup.WithContext(ctx)
+	for seg := range segments {
+		g.Go(func() error {
+			p := dynamodb.NewScanPaginator(r.ddb, &dynamodb.ScanInput{
+				TableName:                aws.String(r.alertsTable),
+				ProjectionExpression:     aws.String("deviceId, #ts, " + dayBucketAttr),
+				ExpressionAttributeNames: map[string]string{"#ts": "ts"},
+				Segment:                  aws.Int32(int32(seg)),
+				TotalSegments:            aws.Int32(int32(segments)),
+			})
+			for p.HasMorePages() {
+				page, err := p.NextPage(ctx)
+				if err != nil {
+					return err
+				}
+				for _, item := range page.Items {
+					scanned.Add(1)
+					if _, ok := item[dayBucketAttr]; ok {
+						skipped.Add(1)
+						continue
+					}
+					ts, ok := item["ts"].(*types.AttributeValueMemberS)
+					if !ok {
+						skipped.Add(1)
+						continue
+					}
+					day := DayBucket(ts.Value)
+					if day == "" {
+						// not in any UTC day; a wrong partition would hide it anyway
+						skipped.Add(1)
+						continue
+					}
+					if dryRun {
+						updated.Add(1)
+						continue
+					}
+					switch err := r.setDayBucket(ctx, item["deviceId"], ts.Value, day); {
+					case err == nil:
+						updated.Add(1)
+					case isConditionFailed(err):
+						skipped.Add(1)
+					default:
+						return err
+					}
+				}
+				if progress != nil {
+					progressMu.Lock()
+					progress(snapshot())
+					progressMu.Unlock()
+				}
+			}
+			return nil
+		})
+	}
+	err := g.Wait()
+	return snapshot(), err
+}
+
+func (r *Repo) setDayBucket(ctx context.Context, deviceID types.AttributeValue, ts, day string) error {
+	return r.breaker.Do(func() error {
+		_, err := r.ddb.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(r.alertsTable),
+			Key: map[string]types.AttributeValue{
+				"deviceId": deviceID,
+				"ts":       &types.AttributeValueMemberS{Value: ts},
+			},
+			ConditionExpression: aws.String("attribute_exists(deviceId) AND attribute_not_exists(" + dayBucketAttr + ")"),
+			UpdateExpression:    aws.String("SET " + dayBucketAttr + " = :d"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":d": &types.AttributeValueMemberS{Value: day},
+			},
+		})
+		return err
+	})
+}
+
+func isConditionFailed(err error) bool {
+	var ccf *types.ConditionalCheckFailedException
+	return errors.As(err, &ccf)
+}
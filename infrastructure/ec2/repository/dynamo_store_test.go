@@ -0,0 +1,130 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository/storetest"
+)
+
+// Runs against DynamoDB Local, e.g.
+//
+//	docker run -p 8000:8000 amazon/dynamodb-local
+//	DYNAMODB_ENDPOINT=http://localhost:8000 go test ./repository/
+func TestDynamoStoreConformance(t *testing.T) {
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("DYNAMODB_ENDPOINT not set")
+	}
+	ddb := dynamodb.New(dynamodb.Options{
+		Region:       "eu-north-1",
+		BaseEndpoint: aws.String(endpoint),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "local", SecretAccessKey: "local"}, nil
+		}),
+	})
+
+	n := 0
+	storetest.Run(t, func(t *testing.T, alerts []models.Alert, sensors []models.Sensor) (repository.AlertStore, repository.SensorStore) {
+		n++
+		suffix := fmt.Sprintf("%d-%d", time.Now().UnixNano(), n)
+		alertsTable := createTable(t, ddb, "alerts-"+suffix, "deviceId", "ts", types.GlobalSecondaryIndex{
+			IndexName: aws.String("byDay"),
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("tsDay"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("ts"), KeyType: types.KeyTypeRange},
+			},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		})
+		devicesTable := createTable(t, ddb, "devices-"+suffix, "deviceId", "")
+
+		for _, a := range alerts {
+			item, err := attributevalue.MarshalMap(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Status == "" {
+				// lambda-alert always sets it, older items may lack it
+				delete(item, "status")
+			}
+			item["tsDay"] = &types.AttributeValueMemberS{Value: repository.DayBucket(a.TS)}
+			putItem(t, ddb, alertsTable, item)
+		}
+		for _, s := range sensors {
+			item := map[string]types.AttributeValue{
+				"deviceId":  &types.AttributeValueMemberS{Value: s.DeviceID},
+				"firstSeen": &types.AttributeValueMemberS{Value: s.FirstSeen.Format(time.RFC3339)},
+				"lastSeen":  &types.AttributeValueMemberS{Value: s.LastSeen.Format(time.RFC3339)},
+				"lat":       &types.AttributeValueMemberN{Value: strconv.FormatFloat(s.Lat, 'f', -1, 64)},
+				"lon":       &types.AttributeValueMemberN{Value: strconv.FormatFloat(s.Lon, 'f', -1, 64)},
+			}
+			if !s.LastHeartbeat.IsZero() {
+				item["lastHeartbeat"] = &types.AttributeValueMemberS{Value: s.LastHeartbeat.Format(time.RFC3339)}
+			}
+			if s.Telemetry != nil {
+				tel, err := attributevalue.MarshalMap(s.Telemetry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				item["telemetry"] = &types.AttributeValueMemberM{Value: tel}
+			}
+			putItem(t, ddb, devicesTable, item)
+		}
+
+		repo := repository.NewRepo(ddb, nil, alertsTable, devicesTable)
+		return repo, repo
+	})
+}
+
+func createTable(t *testing.T, ddb *dynamodb.Client, name, hash, rng string, indexes ...types.GlobalSecondaryIndex) string {
+	t.Helper()
+	ctx := context.Background()
+	in := &dynamodb.CreateTableInput{
+		TableName:   aws.String(name),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String(hash), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String(hash), KeyType: types.KeyTypeHash},
+		},
+	}
+	if rng != "" {
+		in.AttributeDefinitions = append(in.AttributeDefinitions, types.AttributeDefinition{AttributeName: aws.String(rng), AttributeType: types.ScalarAttributeTypeS})
+		in.KeySchema = append(in.KeySchema, types.KeySchemaElement{AttributeName: aws.String(rng), KeyType: types.KeyTypeRange})
+	}
+	defined := map[string]bool{hash: true, rng: true}
+	for _, idx := range indexes {
+		for _, k := range idx.KeySchema {
+			if attr := aws.ToString(k.AttributeName); !defined[attr] {
+				defined[attr] = true
+				in.AttributeDefinitions = append(in.AttributeDefinitions, types.AttributeDefinition{AttributeName: k.AttributeName, AttributeType: types.ScalarAttributeTypeS})
+			}
+		}
+	}
+	in.GlobalSecondaryIndexes = indexes
+	if _, err := ddb.CreateTable(ctx, in); err != nil {
+		t.Fatalf("create table %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		ddb.DeleteTable(context.Background(), &dynamodb.DeleteTableInput{TableName: aws.String(name)})
+	})
+	return name
+}
+
+func putItem(t *testing.T, ddb *dynamodb.Client, table string, item map[string]types.AttributeValue) {
+	t.Helper()
+	if _, err := ddb.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String(table), Item: item}); err != nil {
+		t.Fatal(err)
+	}
+}
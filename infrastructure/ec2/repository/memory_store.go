@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+// MemoryStore is an in-process AlertStore and SensorStore with the same
+// semantics as the DynamoDB tables, for tests and local runs without AWS.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	alerts  map[string]models.Alert
+	sensors map[string]models.Sensor
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		alerts:  make(map[string]models.Alert),
+		sensors: make(map[string]models.Sensor),
+	}
+}
+
+// PutAlert inserts or replaces an alert (what lambda-alert does in DynamoDB).
+func (m *MemoryStore) PutAlert(a models.Alert) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.alerts[a.Key()] = copyAlert(a)
+}
+
+// PutSensor inserts or replaces a sensor (what lambda-register does in DynamoDB).
+func (m *MemoryStore) PutSensor(s models.Sensor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sensors[s.DeviceID] = copySensor(s)
+}
+
+func (m *MemoryStore) GeAlertByPK(_ context.Context, deviceID, ts string, _ bool) (*models.Alert, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	a, ok := m.alerts[deviceID+"#"+ts]
+	if !ok {
+		return nil, nil
+	}
+	a = copyAlert(a)
+	return &a, nil
+}
+
+func (m *MemoryStore) GetAlertsLastHour(_ context.Context) ([]models.Alert, error) {
+	now := time.Now().UTC()
+	from, to := now.Add(-time.Hour).Format(time.RFC3339), now.Format(time.RFC3339)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var res []models.Alert
+	for _, a := range m.alerts {
+		if a.TS >= from && a.TS <= to {
+			res = append(res, copyAlert(a))
+		}
+	}
+	return res, nil
+}
+
+func (m *MemoryStore) GetAlertsByKeys(_ context.Context, keys []string) ([]models.Alert, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var res []models.Alert
+	for _, k := range keys {
+		if a, ok := m.alerts[k]; ok {
+			res = append(res, copyAlert(a))
+		}
+	}
+	return res, nil
+}
+
+// QueryAlerts orders matches newest first (then by deviceId) and pages through
+// them with the same cursor format as the DynamoDB implementation.
+func (m *MemoryStore) QueryAlerts(_ context.Context, q AlertQuery) (AlertPage, error) {
+	q = normalizeQuery(q)
+	startKey, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return AlertPage{}, err
+	}
+	from, to := q.From.UTC().Format(time.RFC3339), q.To.UTC().Format(time.RFC3339)
+
+	m.mu.RLock()
+	var matches []models.Alert
+	for _, a := range m.alerts {
+		if a.TS >= from && a.TS <= to && matchesQuery(a, q) {
+			matches = append(matches, copyAlert(a))
+		}
+	}
+	m.mu.RUnlock()
+
+	return pageAlerts(matches, q, startKey)
+}
+
+// pageAlerts sorts matches newest first (then by deviceId) and cuts the page
+// after startKey, for the stores that filter in memory.
+func pageAlerts(matches []models.Alert, q AlertQuery, startKey map[string]types.AttributeValue) (AlertPage, error) {
+	before := func(a, b models.Alert) bool {
+		if a.TS != b.TS {
+			return a.TS > b.TS
+		}
+		return a.DeviceID < b.DeviceID
+	}
+	sort.Slice(matches, func(i, j int) bool { return before(matches[i], matches[j]) })
+
+	if startKey != nil {
+		last := models.Alert{DeviceID: cursorValue(startKey, "deviceId"), TS: cursorValue(startKey, "ts")}
+		i := sort.Search(len(matches), func(i int) bool { return before(last, matches[i]) })
+		matches = matches[i:]
+	}
+
+	page := AlertPage{Alerts: matches}
+	if len(matches) > q.Limit {
+		page.Alerts = matches[:q.Limit]
+		last := page.Alerts[q.Limit-1]
+		cursor, err := encodeCursor(alertKey(last.DeviceID, last.TS))
+		if err != nil {
+			return AlertPage{}, err
+		}
+		page.NextCursor = cursor
+	}
+	return page, nil
+}
+
+func matchesQuery(a models.Alert, q AlertQuery) bool {
+	if q.DeviceID != "" && a.DeviceID != q.DeviceID {
+		return false
+	}
+	if len(q.Statuses) > 0 {
+		status := a.Status
+		if status == "" {
+			status = models.StatusNew
+		}
+		if !slices.Contains(q.Statuses, status) {
+			return false
+		}
+	}
+	if len(q.Classes) > 0 && !slices.Contains(q.Classes, a.Class) {
+		return false
+	}
+	if q.BBox != nil && !q.BBox.Contains(a.Lat, a.Lon) {
+		return false
+	}
+	return true
+}
+
+func (m *MemoryStore) UpdateAlertStatus(_ context.Context, deviceID, ts, from string, change models.StatusChange) (*models.Alert, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.alerts[deviceID+"#"+ts]
+	if !ok {
+		return nil, ErrStatusConflict
+	}
+	if err := applyStatusChange(&a, from, change); err != nil {
+		return nil, err
+	}
+	m.alerts[a.Key()] = a
+	a = copyAlert(a)
+	return &a, nil
+}
+
+// applyStatusChange moves a to change.To if its status is still from (empty =
+// NEW), like the conditional update in Repo.UpdateAlertStatus.
+func applyStatusChange(a *models.Alert, from string, change models.StatusChange) error {
+	current := a.Status
+	if current == "" {
+		current = models.StatusNew
+	}
+	if from == "" {
+		from = models.StatusNew
+	}
+	if current != from {
+		return ErrStatusConflict
+	}
+	a.Status = change.To
+	a.History = append(slices.Clone(a.History), change)
+	a.UpdatedAt = change.At
+	return nil
+}
+
+func (m *MemoryStore) SetAlertRetention(_ context.Context, deviceID, ts, sourceID string, ttl int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.alerts[deviceID+"#"+ts]
+	if !ok {
+		return nil
+	}
+	setRetention(&a, sourceID, ttl)
+	m.alerts[a.Key()] = a
+	return nil
+}
+
+func setRetention(a *models.Alert, sourceID string, ttl int64) {
+	if sourceID != "" {
+		a.SourceID = sourceID
+	}
+	a.TTL = ttl
+}
+
+func (m *MemoryStore) GetAllSensors(_ context.Context) ([]models.Sensor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	res := make([]models.Sensor, 0, len(m.sensors))
+	for _, s := range m.sensors {
+		res = append(res, copySensor(s))
+	}
+	return res, nil
+}
+
+func copyAlert(a models.Alert) models.Alert {
+	a.History = slices.Clone(a.History)
+	a.AudioURL = ""
+	return a
+}
+
+func copySensor(s models.Sensor) models.Sensor {
+	if s.Telemetry != nil {
+		t := *s.Telemetry
+		s.Telemetry = &t
+	}
+	s.Status = ""
+	return s
+}
+
+func cursorValue(key map[string]types.AttributeValue, name string) string {
+	if v, ok := key[name].(*types.AttributeValueMemberS); ok {
+		return v.Value
+	}
+	return ""
+}
+
+func alertKey(deviceID, ts string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"deviceId": &types.AttributeValueMemberS{Value: deviceID},
+		"ts":       &types.AttributeValueMemberS{Value: ts},
+	}
+}
@@ -0,0 +1,44 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository/storetest"
+)
+
+func TestMemoryStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, alerts []models.Alert, sensors []models.Sensor) (repository.AlertStore, repository.SensorStore) {
+		st := repository.NewMemoryStore()
+		for _, a := range alerts {
+			st.PutAlert(a)
+		}
+		for _, s := range sensors {
+			st.PutSensor(s)
+		}
+		return st, st
+	})
+}
+
+func TestBoltStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, alerts []models.Alert, sensors []models.Sensor) (repository.AlertStore, repository.SensorStore) {
+		st, err := repository.OpenBoltStore(filepath.Join(t.TempDir(), "edge.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { st.Close() })
+		for _, a := range alerts {
+			if err := st.PutAlert(a); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, s := range sensors {
+			if err := st.PutSensor(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return st, st
+	})
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Ping checks are used by the readiness endpoint. They bypass the circuit
+// breakers on purpose: they should report the real state of the dependency,
+// and a failing probe must not open the breaker for regular traffic.
+
+// Ping checks that the alerts and devices tables exist and are usable.
+func (r *Repo) Ping(ctx context.Context) error {
+	for _, t := range []string{r.alertsTable, r.sensorsTable} {
+		if err := describeTable(ctx, r.ddb, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *DynamoSourceStore) Ping(ctx context.Context) error {
+	return describeTable(ctx, r.ddb, r.table)
+}
+
+// Ping checks that the audio bucket is reachable.
+func (r *AudioRepo) Ping(ctx context.Context) error {
+	_, err := r.s3.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(r.bucket)})
+	return err
+}
+
+func describeTable(ctx context.Context, ddb *dynamodb.Client, table string) error {
+	out, err := ddb.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if err != nil {
+		return fmt.Errorf("%s: %w", table, err)
+	}
+	if st := out.Table.TableStatus; st != types.TableStatusActive && st != types.TableStatusUpdating {
+		return fmt.Errorf("%s: table status %s", table, st)
+	}
+	return nil
+}
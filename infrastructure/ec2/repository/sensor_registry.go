@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+// SensorRegistry keeps all sensors of a SensorStore in memory and reloads them
+// periodically (see Run), so GET /sensors and the sensor monitor do not scan
+// the devices table on every call. It is itself a SensorStore.
+type SensorRegistry struct {
+	store  SensorStore
+	logger *log.Logger
+
+	mu       sync.RWMutex
+	sensors  []models.Sensor
+	byID     map[string]int
+	loadedAt time.Time
+}
+
+func NewSensorRegistry(store SensorStore, logger *log.Logger) *SensorRegistry {
+	return &SensorRegistry{store: store, logger: logger}
+}
+
+// Refresh reloads every sensor from the store. On error the previous data is
+// kept.
+func (r *SensorRegistry) Refresh(ctx context.Context) error {
+	sensors, err := r.store.GetAllSensors(ctx)
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]int, len(sensors))
+	for i, s := range sensors {
+		byID[s.DeviceID] = i
+	}
+
+	r.mu.Lock()
+	r.sensors, r.byID, r.loadedAt = sensors, byID, time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the registry every interval until ctx is done.
+func (r *SensorRegistry) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(ctx); err != nil {
+				r.logger.Printf("sensor registry refresh error: %v", err)
+			}
+		}
+	}
+}
+
+// GetAllSensors returns a copy of the cached sensors. The first call loads
+// them if nothing has been loaded yet.
+func (r *SensorRegistry) GetAllSensors(ctx context.Context) ([]models.Sensor, error) {
+	if r.LoadedAt().IsZero() {
+		if err := r.Refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	res := make([]models.Sensor, len(r.sensors))
+	for i, s := range r.sensors {
+		res[i] = copySensor(s)
+	}
+	return res, nil
+}
+
+// Get returns one cached sensor.
+func (r *SensorRegistry) Get(deviceID string) (models.Sensor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	i, ok := r.byID[deviceID]
+	if !ok {
+		return models.Sensor{}, false
+	}
+	return copySensor(r.sensors[i]), true
+}
+
+// Position returns the registered position of a sensor.
+func (r *SensorRegistry) Position(deviceID string) (lat, lon float64, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	i, ok := r.byID[deviceID]
+	if !ok {
+		return 0, 0, false
+	}
+	return r.sensors[i].Lat, r.sensors[i].Lon, true
+}
+
+// LoadedAt is the time of the last successful refresh (zero before the first).
+func (r *SensorRegistry) LoadedAt() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.loadedAt
+}
@@ -0,0 +1,79 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository/storetest"
+)
+
+var discard = log.New(io.Discard, "", 0)
+
+func TestSensorRegistryConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, alerts []models.Alert, sensors []models.Sensor) (repository.AlertStore, repository.SensorStore) {
+		st := repository.NewMemoryStore()
+		for _, a := range alerts {
+			st.PutAlert(a)
+		}
+		for _, s := range sensors {
+			st.PutSensor(s)
+		}
+		return st, repository.NewSensorRegistry(st, discard)
+	})
+}
+
+type flakyStore struct {
+	repository.SensorStore
+	err error
+}
+
+func (f *flakyStore) GetAllSensors(ctx context.Context) ([]models.Sensor, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.SensorStore.GetAllSensors(ctx)
+}
+
+func TestSensorRegistry(t *testing.T) {
+	ctx := context.Background()
+	st := repository.NewMemoryStore()
+	st.PutSensor(models.Sensor{DeviceID: "AAA-001", Lat: 50.06, Lon: 19.94})
+	src := &flakyStore{SensorStore: st}
+	reg := repository.NewSensorRegistry(src, discard)
+
+	if _, _, ok := reg.Position("AAA-001"); ok {
+		t.Fatal("position known before the first load")
+	}
+	if err := reg.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if lat, lon, ok := reg.Position("AAA-001"); !ok || lat != 50.06 || lon != 19.94 {
+		t.Fatalf("Position = %v, %v, %v", lat, lon, ok)
+	}
+
+	// cached: a new sensor shows up only after Refresh
+	st.PutSensor(models.Sensor{DeviceID: "BBB-002"})
+	if got, _ := reg.GetAllSensors(ctx); len(got) != 1 {
+		t.Fatalf("cached sensors = %d", len(got))
+	}
+	if err := reg.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reg.Get("BBB-002"); !ok {
+		t.Fatal("BBB-002 missing after refresh")
+	}
+
+	// a failed refresh keeps the previous data
+	src.err = errors.New("throttled")
+	if err := reg.Refresh(ctx); err == nil {
+		t.Fatal("expected refresh error")
+	}
+	if got, err := reg.GetAllSensors(ctx); err != nil || len(got) != 2 {
+		t.Fatalf("after failed refresh: %d sensors, err %v", len(got), err)
+	}
+}
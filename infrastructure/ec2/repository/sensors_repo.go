@@ -6,22 +6,34 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
 )
 
+// GetAllSensors scans the whole devices table. It is read through
+// SensorRegistry, which caches the result; call it directly only when fresh
+// data is needed.
 func (r *Repo) GetAllSensors(ctx context.Context) ([]models.Sensor, error) {
-	input := &dynamodb.ScanInput{
+	p := dynamodb.NewScanPaginator(r.ddb, &dynamodb.ScanInput{
 		TableName: aws.String(r.sensorsTable),
-	}
-	out, err := r.ddb.Scan(ctx, input)
-	if err != nil {
-		return nil, err
+	})
+	var items []map[string]types.AttributeValue
+	for p.HasMorePages() {
+		var page *dynamodb.ScanOutput
+		err := r.breaker.Do(func() (err error) {
+			page, err = p.NextPage(ctx)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
 	}
 
-	sensors := make([]models.Sensor, 0, len(out.Items))
-	for _, item := range out.Items {
+	sensors := make([]models.Sensor, 0, len(items))
+	for _, item := range items {
 		var s models.Sensor
 
 		if v, ok := item["deviceId"].(*types.AttributeValueMemberS); ok {
@@ -37,6 +49,11 @@ func (r *Repo) GetAllSensors(ctx context.Context) ([]models.Sensor, error) {
 				s.LastSeen = t
 			}
 		}
+		if v, ok := item["lastHeartbeat"].(*types.AttributeValueMemberS); ok {
+			if t, err := time.Parse(time.RFC3339, v.Value); err == nil {
+				s.LastHeartbeat = t
+			}
+		}
 		if v, ok := item["lat"].(*types.AttributeValueMemberN); ok {
 			if f, err := strconv.ParseFloat(v.Value, 64); err == nil {
 				s.Lat = f
@@ -48,6 +65,13 @@ func (r *Repo) GetAllSensors(ctx context.Context) ([]models.Sensor, error) {
 			}
 		}
 
+		if v, ok := item["telemetry"].(*types.AttributeValueMemberM); ok {
+			var t models.Telemetry
+			if err := attributevalue.UnmarshalMap(v.Value, &t); err == nil {
+				s.Telemetry = &t
+			}
+		}
+
 		sensors = append(sensors, s)
 	}
 
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/resilience"
+)
+
+var ErrSourceNotFound = errors.New("source not found")
+
+// SourceStore persists localized sources, including expired ones.
+type SourceStore interface {
+	SaveSource(ctx context.Context, s models.Source) error
+	GetSource(ctx context.Context, id string) (*models.Source, error)
+	// ListSources returns sources in state updated within [from, to], newest first.
+	ListSources(ctx context.Context, state string, from, to time.Time) ([]models.Source, error)
+	ExpireSource(ctx context.Context, id string, at time.Time) error
+}
+
+// DynamoSourceStore keeps sources in their own table (hash key id) with a
+// byState index (state, updatedAt) for listing.
+type DynamoSourceStore struct {
+	ddb     *dynamodb.Client
+	breaker *resilience.Breaker
+	table   string
+}
+
+const sourcesByStateIndex = "byState"
+
+func NewDynamoSourceStore(ddb *dynamodb.Client, breaker *resilience.Breaker, table string) *DynamoSourceStore {
+	return &DynamoSourceStore{ddb: ddb, breaker: breaker, table: table}
+}
+
+func (r *DynamoSourceStore) SaveSource(ctx context.Context, s models.Source) error {
+	item, err := attributevalue.MarshalMap(s)
+	if err != nil {
+		return err
+	}
+	return r.breaker.Do(func() error {
+		_, err := r.ddb.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(r.table),
+			Item:      item,
+		})
+		return err
+	})
+}
+
+func (r *DynamoSourceStore) GetSource(ctx context.Context, id string) (*models.Source, error) {
+	var out *dynamodb.GetItemOutput
+	err := r.breaker.Do(func() (err error) {
+		out, err = r.ddb.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(r.table),
+			Key: map[string]types.AttributeValue{
+				"id": &types.AttributeValueMemberS{Value: id},
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Item == nil {
+		return nil, nil
+	}
+	var s models.Source
+	if err := attributevalue.UnmarshalMap(out.Item, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *DynamoSourceStore) ListSources(ctx context.Context, state string, from, to time.Time) ([]models.Source, error) {
+	p := dynamodb.NewQueryPaginator(r.ddb, &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		IndexName:              aws.String(sourcesByStateIndex),
+		KeyConditionExpression: aws.String("#state = :state AND updatedAt BETWEEN :from AND :to"),
+		ExpressionAttributeNames: map[string]string{
+			"#state": "state",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":state": &types.AttributeValueMemberS{Value: state},
+			":from":  &types.AttributeValueMemberS{Value: from.UTC().Format(time.RFC3339)},
+			":to":    &types.AttributeValueMemberS{Value: to.UTC().Format(time.RFC3339)},
+		},
+		ScanIndexForward: aws.Bool(false),
+	})
+
+	var res []models.Source
+	for p.HasMorePages() {
+		var page *dynamodb.QueryOutput
+		err := r.breaker.Do(func() (err error) {
+			page, err = p.NextPage(ctx)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		var items []models.Source
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, err
+		}
+		res = append(res, items...)
+	}
+	return res, nil
+}
+
+func (r *DynamoSourceStore) ExpireSource(ctx context.Context, id string, at time.Time) error {
+	ts := at.UTC().Format(time.RFC3339)
+	err := r.breaker.Do(func() error {
+		_, err := r.ddb.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(r.table),
+			Key: map[string]types.AttributeValue{
+				"id": &types.AttributeValueMemberS{Value: id},
+			},
+			ConditionExpression: aws.String("attribute_exists(id)"),
+			UpdateExpression:    aws.String("SET #state = :expired, expiredAt = :at, updatedAt = :at"),
+			ExpressionAttributeNames: map[string]string{
+				"#state": "state",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":expired": &types.AttributeValueMemberS{Value: models.SourceExpired},
+				":at":      &types.AttributeValueMemberS{Value: ts},
+			},
+		})
+		return err
+	})
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return ErrSourceNotFound
+	}
+	return err
+}
+
+// MemorySourceStore is an in-process SourceStore, used in tests and when no
+// sources table is configured.
+type MemorySourceStore struct {
+	mu      sync.RWMutex
+	sources map[string]models.Source
+}
+
+func NewMemorySourceStore() *MemorySourceStore {
+	return &MemorySourceStore{sources: make(map[string]models.Source)}
+}
+
+func (m *MemorySourceStore) SaveSource(_ context.Context, s models.Source) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s.AlertKeys = append([]string(nil), s.AlertKeys...)
+	m.sources[s.ID] = s
+	return nil
+}
+
+func (m *MemorySourceStore) GetSource(_ context.Context, id string) (*models.Source, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sources[id]
+	if !ok {
+		return nil, nil
+	}
+	s.AlertKeys = append([]string(nil), s.AlertKeys...)
+	return &s, nil
+}
+
+func (m *MemorySourceStore) ListSources(_ context.Context, state string, from, to time.Time) ([]models.Source, error) {
+	lo, hi := from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var res []models.Source
+	for _, s := range m.sources {
+		if s.State == state && s.UpdatedAt >= lo && s.UpdatedAt <= hi {
+			s.AlertKeys = append([]string(nil), s.AlertKeys...)
+			res = append(res, s)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].UpdatedAt != res[j].UpdatedAt {
+			return res[i].UpdatedAt > res[j].UpdatedAt
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+func (m *MemorySourceStore) ExpireSource(_ context.Context, id string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sources[id]
+	if !ok {
+		return ErrSourceNotFound
+	}
+	ts := at.UTC().Format(time.RFC3339)
+	s.State = models.SourceExpired
+	s.ExpiredAt = ts
+	s.UpdatedAt = ts
+	m.sources[id] = s
+	return nil
+}
+
+// GetAlertsByKeys loads alerts by their deviceId#ts keys. Missing alerts are
+// skipped.
+func (r *Repo) GetAlertsByKeys(ctx context.Context, keys []string) ([]models.Alert, error) {
+	var res []models.Alert
+	for start := 0; start < len(keys); start += 100 {
+		end := min(start+100, len(keys))
+		var reqKeys []map[string]types.AttributeValue
+		for _, k := range keys[start:end] {
+			dev, ts, ok := strings.Cut(k, "#")
+			if !ok {
+				continue
+			}
+			reqKeys = append(reqKeys, map[string]types.AttributeValue{
+				"deviceId": &types.AttributeValueMemberS{Value: dev},
+				"ts":       &types.AttributeValueMemberS{Value: ts},
+			})
+		}
+
+		pending := map[string]types.KeysAndAttributes{r.alertsTable: {Keys: reqKeys}}
+		for attempt := 0; len(pending) > 0 && len(pending[r.alertsTable].Keys) > 0; attempt++ {
+			if attempt > 0 {
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+				}
+			}
+			var out *dynamodb.BatchGetItemOutput
+			err := r.breaker.Do(func() (err error) {
+				out, err = r.ddb.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: pending})
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			var items []models.Alert
+			if err := attributevalue.UnmarshalListOfMaps(out.Responses[r.alertsTable], &items); err != nil {
+				return nil, err
+			}
+			res = append(res, items...)
+			pending = out.UnprocessedKeys
+			if attempt >= 5 {
+				return res, errors.New("batch get: unprocessed keys left after retries")
+			}
+		}
+	}
+	return res, nil
+}
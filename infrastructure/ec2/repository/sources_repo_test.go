@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+func TestMemorySourceStore(t *testing.T) {
+	testSourceStore(t, NewMemorySourceStore())
+}
+
+func TestBoltSourceStore(t *testing.T) {
+	testSourceStore(t, openTestBolt(t))
+}
+
+func testSourceStore(t *testing.T, st SourceStore) {
+	ctx := context.Background()
+	now := time.Date(2025, 12, 3, 20, 0, 0, 0, time.UTC)
+
+	src := models.Source{
+		ID:        "src-1",
+		Lat:       50.06,
+		Lon:       19.93,
+		AlertKeys: []string{"A#t1", "B#t1", "C#t1"},
+		State:     models.SourceActive,
+		FirstSeen: now.Format(time.RFC3339),
+		UpdatedAt: now.Format(time.RFC3339),
+	}
+	if err := st.SaveSource(ctx, src); err != nil {
+		t.Fatal(err)
+	}
+
+	active, _ := st.ListSources(ctx, models.SourceActive, now.Add(-time.Minute), now)
+	if len(active) != 1 || len(active[0].AlertKeys) != 3 {
+		t.Fatalf("active = %+v", active)
+	}
+
+	if err := st.ExpireSource(ctx, "src-1", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.ExpireSource(ctx, "nope", now); !errors.Is(err, ErrSourceNotFound) {
+		t.Fatalf("expire missing: %v", err)
+	}
+
+	got, _ := st.GetSource(ctx, "src-1")
+	if got == nil || got.State != models.SourceExpired || got.Lat != 50.06 {
+		t.Fatalf("expired source = %+v", got)
+	}
+	if active, _ := st.ListSources(ctx, models.SourceActive, now.Add(-time.Hour), now.Add(2*time.Hour)); len(active) != 0 {
+		t.Fatalf("expired source still listed as active")
+	}
+	if expired, _ := st.ListSources(ctx, models.SourceExpired, now, now.Add(2*time.Hour)); len(expired) != 1 {
+		t.Fatalf("expired listing = %+v", expired)
+	}
+}
+
+func TestGetAlertsByKeysStopsOnCancel(t *testing.T) {
+	// every BatchGetItem leaves the key unprocessed
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		io.WriteString(w, `{"Responses":{"alerts":[]},"UnprocessedKeys":{"alerts":{"Keys":[{"deviceId":{"S":"A"},"ts":{"S":"t1"}}]}}}`)
+	}))
+	defer fake.Close()
+	ddb := dynamodb.New(dynamodb.Options{
+		Region:       "eu-north-1",
+		BaseEndpoint: aws.String(fake.URL),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "local", SecretAccessKey: "local"}, nil
+		}),
+	})
+	r := NewRepo(ddb, nil, "alerts", "sensors")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := r.GetAlertsByKeys(ctx, []string{"A#t1"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	// a plain sleep would only notice the deadline after the 100+200 ms waits
+	if d := time.Since(start); d > 250*time.Millisecond {
+		t.Fatalf("returned after %s", d)
+	}
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+)
+
+// AlertStore reads and updates alerts. Implemented by Repo (DynamoDB) and
+// MemoryStore; both must pass the suite in repository/storetest.
+type AlertStore interface {
+	// GeAlertByPK returns nil, nil when the alert does not exist.
+	GeAlertByPK(ctx context.Context, deviceID, ts string, consistent bool) (*models.Alert, error)
+	GetAlertsLastHour(ctx context.Context) ([]models.Alert, error)
+	// GetAlertsByKeys loads alerts by deviceId#ts keys, skipping missing ones.
+	GetAlertsByKeys(ctx context.Context, keys []string) ([]models.Alert, error)
+	QueryAlerts(ctx context.Context, q AlertQuery) (AlertPage, error)
+	// UpdateAlertStatus returns ErrStatusConflict when the alert is missing or
+	// its status is no longer from.
+	UpdateAlertStatus(ctx context.Context, deviceID, ts, from string, change models.StatusChange) (*models.Alert, error)
+	// SetAlertRetention sets the TTL attribute (0 removes it, the alert is kept)
+	// and, if not empty, the source of an alert. A missing alert is not created.
+	SetAlertRetention(ctx context.Context, deviceID, ts, sourceID string, ttl int64) error
+}
+
+// SensorStore reads the sensor registry.
+type SensorStore interface {
+	GetAllSensors(ctx context.Context) ([]models.Sensor, error)
+}
+
+var (
+	_ AlertStore  = (*Repo)(nil)
+	_ SensorStore = (*Repo)(nil)
+	_ AlertStore  = (*MemoryStore)(nil)
+	_ SensorStore = (*MemoryStore)(nil)
+	_ SensorStore = (*SensorRegistry)(nil)
+	_ AlertStore  = (*BoltStore)(nil)
+	_ SensorStore = (*BoltStore)(nil)
+	_ SourceStore = (*BoltStore)(nil)
+)
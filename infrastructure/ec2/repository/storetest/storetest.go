@@ -0,0 +1,324 @@
+// Package storetest is the conformance suite for repository.AlertStore and
+// repository.SensorStore. Every backend runs it from its own test with a
+// factory that returns stores seeded with the given data.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/repository"
+)
+
+// Factory returns fresh stores containing exactly alerts and sensors.
+type Factory func(t *testing.T, alerts []models.Alert, sensors []models.Sensor) (repository.AlertStore, repository.SensorStore)
+
+// Fixed points in time used by the fixtures. Alerts are placed relative to the
+// real clock, because GetAlertsLastHour has no parameters.
+var now = time.Now().UTC().Truncate(time.Second)
+
+func ts(ago time.Duration) string {
+	return now.Add(-ago).Format(time.RFC3339)
+}
+
+func fixtures() ([]models.Alert, []models.Sensor) {
+	alerts := []models.Alert{
+		{DeviceID: "A", TS: ts(5 * time.Minute), Lat: 50.05, Lon: 19.90, Distance: 300, Status: models.StatusNew, Class: "chainsaw", S3Key: "A/1.wav"},
+		{DeviceID: "A", TS: ts(20 * time.Minute), Lat: 50.05, Lon: 19.90, Distance: 250, Class: "gunshot"},
+		{DeviceID: "B", TS: ts(20 * time.Minute), Lat: 50.06, Lon: 19.95, Distance: 400, Status: models.StatusAcknowledged, Class: "chainsaw"},
+		{DeviceID: "B", TS: ts(40 * time.Minute), Lat: 50.06, Lon: 19.95, Distance: 350, Status: models.StatusResolved},
+		{DeviceID: "C", TS: ts(50 * time.Minute), Lat: 50.30, Lon: 20.40, Distance: 500, Status: models.StatusInvestigating, Class: "chainsaw"},
+		{DeviceID: "C", TS: ts(3 * time.Hour), Lat: 50.30, Lon: 20.40, Distance: 500, Status: models.StatusNew},
+	}
+	battery := 3.7
+	sensors := []models.Sensor{
+		{DeviceID: "A", FirstSeen: now.Add(-48 * time.Hour), LastSeen: now.Add(-5 * time.Minute), Lat: 50.05, Lon: 19.90},
+		{DeviceID: "B", FirstSeen: now.Add(-48 * time.Hour), LastSeen: now.Add(-20 * time.Minute), LastHeartbeat: now.Add(-time.Minute), Lat: 50.06, Lon: 19.95,
+			Telemetry: &models.Telemetry{TS: ts(time.Minute), BatteryV: &battery}},
+		{DeviceID: "C", FirstSeen: now.Add(-24 * time.Hour), LastSeen: now.Add(-50 * time.Minute), Lat: 50.30, Lon: 20.40},
+	}
+	return alerts, sensors
+}
+
+// Run runs the whole suite against the backend built by newStores.
+func Run(t *testing.T, newStores Factory) {
+	t.Run("GetByPK", func(t *testing.T) { testGetByPK(t, newStores) })
+	t.Run("GetAlertsByKeys", func(t *testing.T) { testGetAlertsByKeys(t, newStores) })
+	t.Run("GetAlertsLastHour", func(t *testing.T) { testLastHour(t, newStores) })
+	t.Run("QueryAlerts", func(t *testing.T) { testQueryAlerts(t, newStores) })
+	t.Run("UpdateAlertStatus", func(t *testing.T) { testUpdateStatus(t, newStores) })
+	t.Run("SetAlertRetention", func(t *testing.T) { testRetention(t, newStores) })
+	t.Run("GetAllSensors", func(t *testing.T) { testSensors(t, newStores) })
+}
+
+func testGetByPK(t *testing.T, newStores Factory) {
+	ctx := context.Background()
+	alerts, sensors := fixtures()
+	st, _ := newStores(t, alerts, sensors)
+
+	got, err := st.GeAlertByPK(ctx, "A", alerts[0].TS, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.S3Key != "A/1.wav" || got.Distance != 300 || got.Class != "chainsaw" {
+		t.Fatalf("got %+v", got)
+	}
+
+	// returned values must not alias the store
+	got.Status = models.StatusResolved
+	again, _ := st.GeAlertByPK(ctx, "A", alerts[0].TS, false)
+	if again.Status != models.StatusNew {
+		t.Fatalf("store modified through returned alert: %s", again.Status)
+	}
+
+	missing, err := st.GeAlertByPK(ctx, "A", ts(time.Second), true)
+	if err != nil || missing != nil {
+		t.Fatalf("missing alert = %+v, %v", missing, err)
+	}
+}
+
+func testGetAlertsByKeys(t *testing.T, newStores Factory) {
+	alerts, sensors := fixtures()
+	st, _ := newStores(t, alerts, sensors)
+
+	keys := []string{alerts[0].Key(), alerts[3].Key(), "X#" + ts(0), "no-separator"}
+	got, err := st.GetAlertsByKeys(context.Background(), keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertKeys(t, got, alerts[0].Key(), alerts[3].Key())
+}
+
+func testLastHour(t *testing.T, newStores Factory) {
+	alerts, sensors := fixtures()
+	st, _ := newStores(t, alerts, sensors)
+
+	got, err := st.GetAlertsLastHour(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertKeys(t, got, alerts[0].Key(), alerts[1].Key(), alerts[2].Key(), alerts[3].Key(), alerts[4].Key())
+}
+
+func testQueryAlerts(t *testing.T, newStores Factory) {
+	ctx := context.Background()
+	alerts, sensors := fixtures()
+	st, _ := newStores(t, alerts, sensors)
+	window := repository.AlertQuery{From: now.Add(-time.Hour), To: now}
+
+	cases := []struct {
+		name string
+		q    func(q repository.AlertQuery) repository.AlertQuery
+		want []int
+	}{
+		{"window", func(q repository.AlertQuery) repository.AlertQuery { return q }, []int{0, 1, 2, 3, 4}},
+		{"device", func(q repository.AlertQuery) repository.AlertQuery { q.DeviceID = "B"; return q }, []int{2, 3}},
+		// an alert without status counts as NEW
+		{"status new", func(q repository.AlertQuery) repository.AlertQuery { q.Statuses = []string{models.StatusNew}; return q }, []int{0, 1}},
+		{"statuses", func(q repository.AlertQuery) repository.AlertQuery {
+			q.Statuses = []string{models.StatusAcknowledged, models.StatusInvestigating}
+			return q
+		}, []int{2, 4}},
+		{"class", func(q repository.AlertQuery) repository.AlertQuery { q.Classes = []string{"chainsaw"}; return q }, []int{0, 2, 4}},
+		{"bbox", func(q repository.AlertQuery) repository.AlertQuery {
+			q.BBox = &models.BBox{MinLat: 50.0, MinLon: 19.8, MaxLat: 50.1, MaxLon: 20.0}
+			return q
+		}, []int{0, 1, 2, 3}},
+		{"device and class", func(q repository.AlertQuery) repository.AlertQuery {
+			q.DeviceID = "A"
+			q.Classes = []string{"gunshot"}
+			return q
+		}, []int{1}},
+		{"older window", func(q repository.AlertQuery) repository.AlertQuery {
+			q.From, q.To = now.Add(-4*time.Hour), now.Add(-2*time.Hour)
+			return q
+		}, []int{5}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var want []string
+			for _, i := range tc.want {
+				want = append(want, alerts[i].Key())
+			}
+			for _, limit := range []int{1, 2, 100} {
+				q := tc.q(window)
+				q.Limit = limit
+				got := queryAll(t, st, q)
+				assertKeys(t, got, want...)
+			}
+		})
+	}
+
+	t.Run("newest first", func(t *testing.T) {
+		page, err := st.QueryAlerts(ctx, repository.AlertQuery{From: window.From, To: window.To, DeviceID: "A"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Alerts) != 2 || page.Alerts[0].TS < page.Alerts[1].TS {
+			t.Fatalf("page = %+v", page.Alerts)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		_, err := st.QueryAlerts(ctx, repository.AlertQuery{Cursor: "%%%"})
+		if !errors.Is(err, repository.ErrInvalidCursor) {
+			t.Fatalf("err = %v", err)
+		}
+	})
+}
+
+// queryAll follows NextCursor until the last page and fails on duplicates.
+func queryAll(t *testing.T, st repository.AlertStore, q repository.AlertQuery) []models.Alert {
+	t.Helper()
+	var all []models.Alert
+	seen := map[string]bool{}
+	for range 100 {
+		page, err := st.QueryAlerts(context.Background(), q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Alerts) > q.Limit {
+			t.Fatalf("page of %d alerts, limit %d", len(page.Alerts), q.Limit)
+		}
+		for _, a := range page.Alerts {
+			if seen[a.Key()] {
+				t.Fatalf("alert %s returned twice", a.Key())
+			}
+			seen[a.Key()] = true
+		}
+		all = append(all, page.Alerts...)
+		if page.NextCursor == "" {
+			return all
+		}
+		q.Cursor = page.NextCursor
+	}
+	t.Fatal("pagination did not terminate")
+	return nil
+}
+
+func testUpdateStatus(t *testing.T, newStores Factory) {
+	ctx := context.Background()
+	alerts, sensors := fixtures()
+	st, _ := newStores(t, alerts, sensors)
+
+	// alerts[1] has no status: NEW
+	change := models.StatusChange{From: models.StatusNew, To: models.StatusAcknowledged, Operator: "ranger", Note: "on my way", At: ts(0)}
+	got, err := st.UpdateAlertStatus(ctx, "A", alerts[1].TS, "", change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.StatusAcknowledged || got.UpdatedAt != change.At || len(got.History) != 1 || got.History[0] != change {
+		t.Fatalf("updated = %+v", got)
+	}
+	if got.Lat != alerts[1].Lat || got.Distance != alerts[1].Distance {
+		t.Fatalf("other attributes lost: %+v", got)
+	}
+
+	// stale from: someone else already moved it
+	if _, err := st.UpdateAlertStatus(ctx, "A", alerts[1].TS, models.StatusNew, change); !errors.Is(err, repository.ErrStatusConflict) {
+		t.Fatalf("stale update err = %v", err)
+	}
+
+	next := models.StatusChange{From: models.StatusAcknowledged, To: models.StatusResolved, Operator: "ranger", At: ts(0)}
+	got, err = st.UpdateAlertStatus(ctx, "A", alerts[1].TS, models.StatusAcknowledged, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.History) != 2 || got.History[1].To != models.StatusResolved {
+		t.Fatalf("history = %+v", got.History)
+	}
+
+	stored, _ := st.GeAlertByPK(ctx, "A", alerts[1].TS, true)
+	if stored.Status != models.StatusResolved || len(stored.History) != 2 {
+		t.Fatalf("stored = %+v", stored)
+	}
+
+	if _, err := st.UpdateAlertStatus(ctx, "X", ts(0), "", change); !errors.Is(err, repository.ErrStatusConflict) {
+		t.Fatalf("missing alert err = %v", err)
+	}
+}
+
+func testRetention(t *testing.T, newStores Factory) {
+	ctx := context.Background()
+	alerts, sensors := fixtures()
+	st, _ := newStores(t, alerts, sensors)
+	a := alerts[0]
+	ttl := now.Add(30 * 24 * time.Hour).Unix()
+
+	if err := st.SetAlertRetention(ctx, a.DeviceID, a.TS, "", ttl); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := st.GeAlertByPK(ctx, a.DeviceID, a.TS, true)
+	if got.TTL != ttl || got.SourceID != "" || got.Distance != a.Distance {
+		t.Fatalf("after ttl: %+v", got)
+	}
+
+	if err := st.SetAlertRetention(ctx, a.DeviceID, a.TS, "src-1", ttl+1); err != nil {
+		t.Fatal(err)
+	}
+	// no source given: the stored one stays, 0 = kept forever
+	if err := st.SetAlertRetention(ctx, a.DeviceID, a.TS, "", 0); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = st.GeAlertByPK(ctx, a.DeviceID, a.TS, true)
+	if got.TTL != 0 || got.SourceID != "src-1" {
+		t.Fatalf("after exempt: %+v", got)
+	}
+
+	// already expired: must not come back as an empty item
+	if err := st.SetAlertRetention(ctx, "X", ts(0), "src-1", ttl); err != nil {
+		t.Fatalf("missing alert err = %v", err)
+	}
+	if got, _ := st.GeAlertByPK(ctx, "X", ts(0), true); got != nil {
+		t.Fatalf("missing alert created: %+v", got)
+	}
+}
+
+func testSensors(t *testing.T, newStores Factory) {
+	alerts, sensors := fixtures()
+	_, st := newStores(t, alerts, sensors)
+
+	got, err := st.GetAllSensors(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].DeviceID < got[j].DeviceID })
+	if len(got) != len(sensors) {
+		t.Fatalf("got %d sensors, want %d", len(got), len(sensors))
+	}
+	for i, want := range sensors {
+		s := got[i]
+		if s.DeviceID != want.DeviceID || s.Lat != want.Lat || s.Lon != want.Lon ||
+			!s.FirstSeen.Equal(want.FirstSeen) || !s.LastSeen.Equal(want.LastSeen) || !s.LastHeartbeat.Equal(want.LastHeartbeat) {
+			t.Errorf("sensor %d = %+v, want %+v", i, s, want)
+		}
+		if (s.Telemetry == nil) != (want.Telemetry == nil) {
+			t.Errorf("sensor %s telemetry = %+v", s.DeviceID, s.Telemetry)
+		} else if want.Telemetry != nil && (s.Telemetry.BatteryV == nil || *s.Telemetry.BatteryV != *want.Telemetry.BatteryV) {
+			t.Errorf("sensor %s telemetry = %+v", s.DeviceID, s.Telemetry)
+		}
+	}
+}
+
+func assertKeys(t *testing.T, got []models.Alert, want ...string) {
+	t.Helper()
+	keys := make([]string, len(got))
+	for i, a := range got {
+		keys[i] = a.Key()
+	}
+	sort.Strings(keys)
+	sort.Strings(want)
+	if len(keys) != len(want) {
+		t.Fatalf("got %v, want %v", keys, want)
+	}
+	for i := range keys {
+		if keys[i] != want[i] {
+			t.Fatalf("got %v, want %v", keys, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/models"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/resilience"
+)
+
+// MaxTelemetrySamples caps a single telemetry query; at one sample a minute it
+// is about a month of data for one sensor.
+const MaxTelemetrySamples = 50000
+
+// TelemetryRepo reads sensor telemetry samples (written by lambda-heartbeat,
+// expired by DynamoDB TTL).
+type TelemetryRepo struct {
+	ddb     *dynamodb.Client
+	breaker *resilience.Breaker
+	table   string
+}
+
+func NewTelemetryRepo(ddb *dynamodb.Client, breaker *resilience.Breaker, table string) *TelemetryRepo {
+	return &TelemetryRepo{ddb: ddb, breaker: breaker, table: table}
+}
+
+// Query returns the samples of one sensor with from <= ts <= to, oldest first.
+func (r *TelemetryRepo) Query(ctx context.Context, deviceID string, from, to time.Time) ([]models.Telemetry, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		KeyConditionExpression: aws.String("deviceId = :d AND #ts BETWEEN :from AND :to"),
+		ExpressionAttributeNames: map[string]string{
+			"#ts": "ts",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":d":    &types.AttributeValueMemberS{Value: deviceID},
+			":from": &types.AttributeValueMemberS{Value: from.UTC().Format(time.RFC3339)},
+			":to":   &types.AttributeValueMemberS{Value: to.UTC().Format(time.RFC3339)},
+		},
+	}
+
+	var all []models.Telemetry
+	p := dynamodb.NewQueryPaginator(r.ddb, input)
+	for p.HasMorePages() {
+		var page *dynamodb.QueryOutput
+		err := r.breaker.Do(func() (err error) {
+			page, err = p.NextPage(ctx)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		var samples []models.Telemetry
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &samples); err != nil {
+			return nil, err
+		}
+		all = append(all, samples...)
+		if len(all) > MaxTelemetrySamples {
+			return nil, fmt.Errorf("more than %d telemetry samples in range", MaxTelemetrySamples)
+		}
+	}
+	return all, nil
+}
+
+func (r *TelemetryRepo) Ping(ctx context.Context) error {
+	return describeTable(ctx, r.ddb, r.table)
+}
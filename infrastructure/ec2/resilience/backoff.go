@@ -0,0 +1,43 @@
+package resilience
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff produces exponentially growing delays with jitter. The n-th delay is
+// drawn uniformly from [d/2, d) where d = min(Max, Base*2^n), so retries from
+// several workers do not line up.
+type Backoff struct {
+	Base    time.Duration
+	Max     time.Duration
+	attempt int
+}
+
+func NewBackoff(base, max time.Duration) *Backoff {
+	return &Backoff{Base: base, Max: max}
+}
+
+func (b *Backoff) Next() time.Duration {
+	d := b.Max
+	if b.attempt < 32 {
+		if exp := b.Base << b.attempt; exp > 0 && exp < b.Max {
+			d = exp
+		}
+	}
+	b.attempt++
+
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half)
+}
+
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
+
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
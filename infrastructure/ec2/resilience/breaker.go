@@ -0,0 +1,166 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/smithy-go"
+)
+
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half_open"
+)
+
+var ErrOpen = errors.New("circuit breaker open")
+
+// Breaker is a simple consecutive-failure circuit breaker. After threshold
+// failures in a row it opens and rejects calls for cooldown; then a single trial
+// call is let through (half-open) and its result decides whether it closes again.
+// A nil *Breaker lets every call through.
+type Breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	openedAt  time.Time
+	lastError string
+	trial     bool
+}
+
+type Status struct {
+	Name                string    `json:"name"`
+	State               State     `json:"state"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	OpenedAt            time.Time `json:"openedAt,omitzero"`
+	RetryAt             time.Time `json:"retryAt,omitzero"`
+	LastError           string    `json:"lastError,omitempty"`
+}
+
+func NewBreaker(name string, threshold int, cooldown time.Duration) *Breaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &Breaker{name: name, threshold: threshold, cooldown: cooldown, state: StateClosed}
+}
+
+func (b *Breaker) Name() string {
+	if b == nil {
+		return ""
+	}
+	return b.name
+}
+
+// Do runs fn unless the breaker is open and records its outcome.
+func (b *Breaker) Do(fn func() error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.record(err)
+	return err
+}
+
+// Ready reports whether a call would currently be let through, without
+// reserving the half-open trial slot.
+func (b *Breaker) Ready() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case StateOpen:
+		return time.Since(b.openedAt) >= b.cooldown
+	case StateHalfOpen:
+		return !b.trial
+	}
+	return true
+}
+
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := Status{
+		Name:                b.name,
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastError,
+	}
+	if b.state != StateClosed {
+		st.OpenedAt = b.openedAt
+		st.RetryAt = b.openedAt.Add(b.cooldown)
+	}
+	return st
+}
+
+func (b *Breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrOpen
+		}
+		b.state = StateHalfOpen
+		b.trial = true
+	case StateHalfOpen:
+		if b.trial {
+			return ErrOpen
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+func (b *Breaker) record(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+
+	if !isFailure(err) {
+		b.state = StateClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.state = StateOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// isFailure decides whether an error says something about the health of the
+// remote service. Cancellations and client-side API errors (validation,
+// conditional check failures, missing keys) do not trip the breaker.
+func isFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorFault() == smithy.FaultClient {
+		switch apiErr.ErrorCode() {
+		case "ThrottlingException", "ProvisionedThroughputExceededException", "RequestLimitExceeded",
+			"RequestThrottled", "SlowDown", "OverLimit":
+			return true
+		}
+		return false
+	}
+	return true
+}
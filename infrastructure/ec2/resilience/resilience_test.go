@@ -0,0 +1,79 @@
+package resilience
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoffGrowsAndStaysBounded(t *testing.T) {
+	b := NewBackoff(100*time.Millisecond, 2*time.Second)
+	prevCeil := time.Duration(0)
+	for i := 0; i < 10; i++ {
+		ceil := min(100*time.Millisecond<<i, 2*time.Second)
+		d := b.Next()
+		if d < ceil/2 || d >= ceil {
+			t.Fatalf("attempt %d: delay %s outside [%s, %s)", i, d, ceil/2, ceil)
+		}
+		if ceil < prevCeil {
+			t.Fatalf("ceiling shrank")
+		}
+		prevCeil = ceil
+	}
+
+	b.Reset()
+	if d := b.Next(); d >= 100*time.Millisecond {
+		t.Fatalf("after reset delay = %s", d)
+	}
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	boom := errors.New("boom")
+	b := NewBreaker("ddb", 3, 20*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if err := b.Do(func() error { return boom }); !errors.Is(err, boom) {
+			t.Fatalf("call %d: err = %v", i, err)
+		}
+	}
+	if st := b.Status(); st.State != StateOpen {
+		t.Fatalf("state = %s, want open", st.State)
+	}
+
+	called := false
+	if err := b.Do(func() error { called = true; return nil }); !errors.Is(err, ErrOpen) || called {
+		t.Fatalf("open breaker let call through: err=%v called=%v", err, called)
+	}
+	if b.Ready() {
+		t.Fatal("open breaker reports ready before cooldown")
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if !b.Ready() {
+		t.Fatal("breaker not ready after cooldown")
+	}
+
+	// failed trial re-opens immediately
+	_ = b.Do(func() error { return boom })
+	if st := b.Status(); st.State != StateOpen {
+		t.Fatalf("state after failed trial = %s", st.State)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if err := b.Do(func() error { return nil }); err != nil {
+		t.Fatalf("trial call: %v", err)
+	}
+	if st := b.Status(); st.State != StateClosed || st.ConsecutiveFailures != 0 {
+		t.Fatalf("status after recovery = %+v", st)
+	}
+}
+
+func TestNilBreakerPassesThrough(t *testing.T) {
+	var b *Breaker
+	if err := b.Do(func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if !b.Ready() {
+		t.Fatal("nil breaker not ready")
+	}
+}
@@ -1,31 +1,107 @@
 package router
 
 import (
+	"fmt"
+	"regexp"
+	"time"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/api"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/auth"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/edge"
 	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/handlers"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/metrics"
 )
 
-func SetupRouter(handler *handlers.Handler) *gin.Engine {
-	r := gin.Default()
+// SetupRouter builds the API. ingest is nil unless the worker runs as an edge
+// gateway.
+func SetupRouter(handler *handlers.Handler, admin *handlers.AdminHandler, health *handlers.HealthHandler, events *handlers.StreamHandler, ingest *edge.Ingest, authn *auth.Authenticator, corsOrigins []string) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.LoggerWithFormatter(logFormatter), gin.Recovery(), metrics.Middleware())
 
 	// Setup CORS middleware
-	r.Use(cors.Default())
-
-	sensors := r.Group("/sensors")
-	{
-		sensors.GET("", handler.ListSensors)
+	corsCfg := cors.DefaultConfig()
+	if len(corsOrigins) > 0 {
+		corsCfg.AllowOrigins = corsOrigins
+	} else {
+		corsCfg.AllowAllOrigins = true
 	}
+	corsCfg.AddAllowHeaders("Authorization", "X-Operator")
+	r.Use(cors.New(corsCfg))
 
-	sources := r.Group("/sources")
-	{
-		sources.GET("", handler.ListSources)
+	// public
+	r.GET("/openapi.json", gin.WrapF(api.ServeSpec))
+	r.GET("/healthz", health.Liveness)
+	r.GET("/readyz", health.Readiness)
+
+	// edge gateway: sensors post here instead of to lambda-alert /
+	// lambda-heartbeat; not part of the client API (api.Operations)
+	if ingest != nil {
+		in := r.Group("/ingest", ingest.Authenticate())
+		in.POST("/alert", ingest.Alert)
+		in.POST("/heartbeat", ingest.Heartbeat)
 	}
 
-	alerts := r.Group("/alerts")
+	r.Use(authn.Authenticate())
+	viewer := r.Group("", auth.Require(auth.RoleViewer))
+	ranger := r.Group("", auth.Require(auth.RoleRanger))
+	adminOnly := r.Group("", auth.Require(auth.RoleAdmin))
+
+	viewer.GET("/stream", events.Stream)
+
+	// GeoJSON variants of the list endpoints (also via Accept: application/geo+json)
+	viewer.GET("/sensors.geojson", handler.ListSensors)
+	viewer.GET("/sources.geojson", handler.ListSources)
+	viewer.GET("/alerts.geojson", handler.ListAlerts)
+
+	// field exports for rangers (Google Earth, handheld GPS)
+	viewer.GET("/sources.kml", handler.ExportSourcesKML)
+	viewer.GET("/sources.gpx", handler.ExportSourcesGPX)
+
+	viewer.GET("/sensors", handler.ListSensors)
+	viewer.GET("/sensors/:id/telemetry", handler.SensorTelemetry)
+
+	viewer.GET("/sources", handler.ListSources)
+	viewer.GET("/sources/:id", handler.GetSource)
+	viewer.GET("/sources/:id/map.png", handler.SourceMap)
+
+	viewer.GET("/alerts", handler.ListAlerts)
+	viewer.GET("/alerts/:deviceId/:ts", handler.GetAlert)
+	ranger.PATCH("/alerts/:deviceId/:ts", handler.UpdateAlertStatus)
+	ranger.GET("/alerts/:deviceId/:ts/audio", handler.StreamAudio)
+	ranger.GET("/alerts/:deviceId/:ts/media/:kind", handler.StreamMedia)
+
+	// details of the probes above and the Prometheus scrape
+	adminOnly.GET("/health", health.Health)
+	adminOnly.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	dlq := adminOnly.Group("/admin/queues/:queue")
 	{
-		alerts.GET("", handler.ListAlerts)
+		dlq.GET("/messages", admin.ListMessages)
+		dlq.DELETE("/messages", admin.PurgeQueue)
+		dlq.GET("/messages/:id", admin.GetMessage)
+		dlq.POST("/messages/:id/redrive", admin.RedriveMessage)
+		dlq.DELETE("/messages/:id", admin.PurgeMessage)
 	}
 
 	return r
 }
+
+var accessToken = regexp.MustCompile(`access_token=[^&]*`)
+
+// logFormatter is gin's default access log line with ?access_token= redacted.
+func logFormatter(p gin.LogFormatterParams) string {
+	if p.Latency > time.Minute {
+		p.Latency = p.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+		p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		p.StatusCode,
+		p.Latency,
+		p.ClientIP,
+		p.Method,
+		accessToken.ReplaceAllString(p.Path, "access_token=REDACTED"),
+		p.ErrorMessage,
+	)
+}
@@ -0,0 +1,138 @@
+package router
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/api"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/auth"
+	"github.com/maciej-klimek/sound-based-forest-monitoring/infrastructure/ec2/handlers"
+)
+
+func testRouter(authn *auth.Authenticator) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	return SetupRouter(&handlers.Handler{}, &handlers.AdminHandler{}, &handlers.HealthHandler{}, &handlers.StreamHandler{}, nil, authn, nil)
+}
+
+// Every route must be described in api.Operations (and so in /openapi.json
+// and the generated client), and every operation must be routed.
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	r := testRouter(auth.Disabled())
+
+	routed := map[string]bool{}
+	for _, rt := range r.Routes() {
+		routed[rt.Method+" "+rt.Path] = true
+	}
+	described := map[string]bool{}
+	for _, op := range api.Operations {
+		described[op.Method+" "+op.Path] = true
+	}
+
+	var missing, stale []string
+	for k := range routed {
+		if !described[k] {
+			missing = append(missing, k)
+		}
+	}
+	for k := range described {
+		if !routed[k] {
+			stale = append(stale, k)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+	if len(missing) > 0 {
+		t.Errorf("routes missing from api.Operations: %v", missing)
+	}
+	if len(stale) > 0 {
+		t.Errorf("api.Operations without a route: %v", stale)
+	}
+}
+
+// The role in api.Operations must be what the router enforces: no token gives
+// 401 and a token with the next lower role gives 403.
+func TestRoutesEnforceRoles(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "EC", "crv": "P-256", "kid": "test", "use": "sig",
+		"x": base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y": base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.NewKeySet(context.Background(), path, log.New(os.Stderr, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := testRouter(auth.New(keys, auth.Options{Audience: "forest-api"}))
+
+	token := func(roles ...string) string {
+		tok := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+			"sub": "u1", "aud": "forest-api", "roles": roles,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		tok.Header["kid"] = "test"
+		s, err := tok.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	below := map[string][]string{
+		auth.RoleViewer: nil,
+		auth.RoleRanger: {auth.RoleViewer},
+		auth.RoleAdmin:  {auth.RoleRanger},
+	}
+	do := func(method, path, tok string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if tok != "" {
+			req.Header.Set("Authorization", "Bearer "+tok)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, op := range api.Operations {
+		path := op.Path
+		for _, p := range api.PathParams(op.Path) {
+			path = strings.Replace(path, ":"+p, "x", 1)
+		}
+		if op.Role == "" {
+			if code := do(op.Method, path, ""); code == http.StatusUnauthorized {
+				t.Errorf("%s %s: public route returned 401", op.Method, op.Path)
+			}
+			continue
+		}
+		if code := do(op.Method, path, ""); code != http.StatusUnauthorized {
+			t.Errorf("%s %s without token: %d, want 401", op.Method, op.Path, code)
+		}
+		if code := do(op.Method, path, token(below[op.Role]...)); code != http.StatusForbidden {
+			t.Errorf("%s %s as %v: %d, want 403", op.Method, op.Path, below[op.Role], code)
+		}
+	}
+
+	if code := do("GET", "/alerts", "not-a-jwt"); code != http.StatusUnauthorized {
+		t.Errorf("invalid token: %d, want 401", code)
+	}
+}
@@ -0,0 +1,169 @@
+package stream
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EventAlertCreated  = "alert.created"
+	EventSourceCreated = "source.created"
+	EventSourceUpdated = "source.updated"
+	EventSourceExpired = "source.expired"
+	EventSensorOffline = "sensor.offline"
+	EventSensorOnline  = "sensor.online"
+)
+
+// Event IDs are "<epoch>-<seq>": seq restarts at 1 with every worker process,
+// the epoch (boot time in Unix nanoseconds) tells the sequences apart.
+type Event struct {
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	At   time.Time       `json:"at"`
+	Data json.RawMessage `json:"data"`
+	seq  uint64
+}
+
+// Broker fans events out to subscribers and keeps the last historySize events
+// so that reconnecting clients can resume from their Last-Event-ID. A client
+// whose buffer fills up is disconnected rather than blocking publishers; it is
+// expected to reconnect and resume.
+type Broker struct {
+	mu         sync.Mutex
+	epoch      string
+	nextID     uint64
+	history    []Event
+	historyCap int
+	bufferSize int
+	clients    map[*Client]struct{}
+	closed     bool
+}
+
+type Client struct {
+	events chan Event
+	types  map[string]bool
+}
+
+// Events is closed when the client is dropped or the broker shuts down.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+func NewBroker(historySize, bufferSize int) *Broker {
+	return &Broker{
+		epoch:      strconv.FormatInt(time.Now().UnixNano(), 10),
+		historyCap: historySize,
+		bufferSize: bufferSize,
+		clients:    make(map[*Client]struct{}),
+	}
+}
+
+// Publish marshals data and delivers it to all subscribers. A nil broker
+// ignores the call.
+func (b *Broker) Publish(typ string, data any) {
+	if b == nil {
+		return
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	b.nextID++
+	ev := Event{ID: b.epoch + "-" + strconv.FormatUint(b.nextID, 10), Type: typ, At: time.Now().UTC(), Data: raw, seq: b.nextID}
+	b.history = append(b.history, ev)
+	if over := len(b.history) - b.historyCap; over > 0 {
+		b.history = append(b.history[:0], b.history[over:]...)
+	}
+
+	for c := range b.clients {
+		if !c.wants(typ) {
+			continue
+		}
+		select {
+		case c.events <- ev:
+		default:
+			delete(b.clients, c)
+			close(c.events)
+		}
+	}
+}
+
+// Subscribe registers a client interested in types (all when empty). Events
+// newer than lastEventID that are still in history are returned as backlog;
+// complete is false when some of them have already been evicted, or when
+// lastEventID is not from this process (restart, malformed ID) and nothing
+// can be said about what the client missed.
+func (b *Broker) Subscribe(lastEventID string, types []string) (c *Client, backlog []Event, complete bool) {
+	c = &Client{events: make(chan Event, b.bufferSize)}
+	if len(types) > 0 {
+		c.types = make(map[string]bool, len(types))
+		for _, t := range types {
+			c.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(c.events)
+		return c, nil, true
+	}
+	b.clients[c] = struct{}{}
+
+	if lastEventID == "" {
+		return c, nil, true
+	}
+	epoch, seqStr, _ := strings.Cut(lastEventID, "-")
+	since, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil || epoch != b.epoch || since > b.nextID {
+		return c, nil, false
+	}
+	complete = len(b.history) == 0 || b.history[0].seq <= since+1
+	for _, ev := range b.history {
+		if ev.seq > since && c.wants(ev.Type) {
+			backlog = append(backlog, ev)
+		}
+	}
+	return c, backlog, complete
+}
+
+func (b *Broker) Unsubscribe(c *Client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.clients[c]; ok {
+		delete(b.clients, c)
+		close(c.events)
+	}
+}
+
+// Close disconnects all clients; used on shutdown so that open streams do not
+// hold http.Server.Shutdown until its deadline.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for c := range b.clients {
+		delete(b.clients, c)
+		close(c.events)
+	}
+}
+
+func (b *Broker) Clients() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.clients)
+}
+
+func (c *Client) wants(typ string) bool {
+	return c.types == nil || c.types[typ]
+}
@@ -0,0 +1,99 @@
+package stream
+
+import (
+	"strconv"
+	"testing"
+)
+
+func eventID(b *Broker, seq uint64) string {
+	return b.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+func TestBrokerResumeFromLastEventID(t *testing.T) {
+	b := NewBroker(3, 8)
+	for i := 0; i < 5; i++ {
+		b.Publish(EventAlertCreated, i)
+	}
+
+	// ids 3..5 are still in history
+	c, backlog, complete := b.Subscribe(eventID(b, 3), nil)
+	defer b.Unsubscribe(c)
+	if !complete {
+		t.Fatal("expected complete backlog")
+	}
+	if len(backlog) != 2 || backlog[0].ID != eventID(b, 4) || backlog[1].ID != eventID(b, 5) {
+		t.Fatalf("backlog = %+v", backlog)
+	}
+
+	_, _, complete = b.Subscribe(eventID(b, 1), nil)
+	if complete {
+		t.Fatal("expected gap after eviction")
+	}
+
+	b.Publish(EventSourceCreated, "x")
+	ev := <-c.Events()
+	if ev.ID != eventID(b, 6) || ev.Type != EventSourceCreated || string(ev.Data) != `"x"` {
+		t.Fatalf("live event = %+v", ev)
+	}
+}
+
+func TestBrokerResumeAfterRestart(t *testing.T) {
+	old := NewBroker(10, 8)
+	for i := 0; i < 3; i++ {
+		old.Publish(EventAlertCreated, i)
+	}
+	stale := eventID(old, 3)
+
+	// the new process has already issued more IDs than the client has seen
+	b := NewBroker(10, 8)
+	b.epoch = old.epoch + "0"
+	for i := 0; i < 5; i++ {
+		b.Publish(EventAlertCreated, i)
+	}
+	if _, backlog, complete := b.Subscribe(stale, nil); complete || len(backlog) != 0 {
+		t.Fatalf("stale id: complete=%v backlog=%+v", complete, backlog)
+	}
+	for _, id := range []string{"3", "x-1", eventID(b, 9)} {
+		if _, _, complete := b.Subscribe(id, nil); complete {
+			t.Fatalf("id %q reported as complete", id)
+		}
+	}
+	if _, backlog, complete := b.Subscribe(eventID(b, 5), nil); !complete || len(backlog) != 0 {
+		t.Fatal("client up to date reported as incomplete")
+	}
+}
+
+func TestBrokerTypeFilter(t *testing.T) {
+	b := NewBroker(10, 8)
+	c, _, _ := b.Subscribe("", []string{EventSourceExpired})
+	b.Publish(EventAlertCreated, 1)
+	b.Publish(EventSourceExpired, 2)
+	if ev := <-c.Events(); ev.Type != EventSourceExpired {
+		t.Fatalf("got %s", ev.Type)
+	}
+}
+
+func TestBrokerDropsSlowClient(t *testing.T) {
+	b := NewBroker(10, 1)
+	c, _, _ := b.Subscribe("", nil)
+	b.Publish(EventAlertCreated, 1)
+	b.Publish(EventAlertCreated, 2) // buffer full -> dropped
+
+	if b.Clients() != 0 {
+		t.Fatalf("slow client still subscribed")
+	}
+	<-c.Events()
+	if _, ok := <-c.Events(); ok {
+		t.Fatal("channel of dropped client not closed")
+	}
+}
+
+func TestBrokerClose(t *testing.T) {
+	b := NewBroker(10, 1)
+	c, _, _ := b.Subscribe("", nil)
+	b.Close()
+	if _, ok := <-c.Events(); ok {
+		t.Fatal("expected closed channel")
+	}
+	b.Publish(EventAlertCreated, 1)
+}
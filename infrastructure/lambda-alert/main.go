@@ -29,6 +29,7 @@ type alertReq struct {
 	Lat      float64 `json:"lat"`
 	Lon      float64 `json:"lon"`
 	Distance float64 `json:"distance"`
+	Class    string  `json:"class"`
 	AudioB64 string  `json:"audioB64"`
 }
 
@@ -45,6 +46,9 @@ var (
 	alertsTbl   string
 	devicesTbl  string
 	audioBucket string
+	// routine alerts (no source yet) expire after this; the worker extends it
+	// when the alert joins a source
+	alertTTL time.Duration
 )
 
 func init() {
@@ -57,6 +61,11 @@ func init() {
 	alertsTbl = os.Getenv("ALERTS_TABLE")
 	devicesTbl = os.Getenv("DEVICES_TABLE")
 	audioBucket = os.Getenv("AUDIO_BUCKET")
+	days, err := strconv.Atoi(os.Getenv("ALERT_TTL_DAYS"))
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	alertTTL = time.Duration(days) * 24 * time.Hour
 }
 
 func handler(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
@@ -70,9 +79,16 @@ func handler(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.AP
 	if in.DeviceID == "" || in.AudioB64 == "" {
 		return jsonResp(400, map[string]string{"error": "deviceId and audioB64 required"})
 	}
-	if strings.TrimSpace(in.TS) == "" {
-		in.TS = time.Now().UTC().Format(time.RFC3339)
+	ts := time.Now().UTC()
+	if strings.TrimSpace(in.TS) != "" {
+		t, err := time.Parse(time.RFC3339, in.TS)
+		if err != nil {
+			return jsonResp(400, map[string]string{"error": "ts must be RFC3339"})
+		}
+		ts = t.UTC()
 	}
+	// the key, tsDay (byDay index) and S3 path are all derived from the UTC form
+	in.TS = ts.Format(time.RFC3339)
 
 	audioBytes, err := base64.StdEncoding.DecodeString(in.AudioB64)
 	if err != nil {
@@ -109,6 +125,7 @@ func handler(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.AP
 	item := map[string]ddbt.AttributeValue{
 		"deviceId":  &ddbt.AttributeValueMemberS{Value: in.DeviceID},
 		"ts":        &ddbt.AttributeValueMemberS{Value: in.TS},
+		"tsDay":     &ddbt.AttributeValueMemberS{Value: in.TS[:10]}, // partition of the byDay index
 		"s3Key":     &ddbt.AttributeValueMemberS{Value: key},
 		"lat":       &ddbt.AttributeValueMemberN{Value: strconv.FormatFloat(in.Lat, 'f', -1, 64)},
 		"lon":       &ddbt.AttributeValueMemberN{Value: strconv.FormatFloat(in.Lon, 'f', -1, 64)},
@@ -117,6 +134,10 @@ func handler(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.AP
 		"checksum":  &ddbt.AttributeValueMemberS{Value: sha},
 		"createdAt": &ddbt.AttributeValueMemberS{Value: now},
 	}
+	item["ttl"] = &ddbt.AttributeValueMemberN{Value: strconv.FormatInt(ts.Add(alertTTL).Unix(), 10)}
+	if c := strings.TrimSpace(in.Class); c != "" {
+		item["class"] = &ddbt.AttributeValueMemberS{Value: strings.ToLower(c)}
+	}
 	_, err = ddb.PutItem(ctx, &dynamodb.PutItemInput{
 		TableName: aws.String(alertsTbl),
 		Item:      item,
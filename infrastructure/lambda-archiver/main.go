@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var (
+	s3c         *s3.Client
+	archiveBkt  string
+	archivePfx  string
+	audioBucket string
+)
+
+func init() {
+	archiveBkt = os.Getenv("ARCHIVE_BUCKET")
+	if archiveBkt == "" {
+		log.Fatal("ARCHIVE_BUCKET env is required")
+	}
+	archivePfx = strings.TrimSuffix(os.Getenv("ARCHIVE_PREFIX"), "/")
+	if archivePfx == "" {
+		archivePfx = "archive/alerts"
+	}
+	audioBucket = os.Getenv("AUDIO_BUCKET")
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	s3c = s3.NewFromConfig(cfg)
+}
+
+// expiredByTTL reports whether DynamoDB TTL deleted the item (and not e.g. an
+// operator with DeleteItem).
+func expiredByTTL(r events.DynamoDBEventRecord) bool {
+	return r.EventName == "REMOVE" && r.UserIdentity != nil &&
+		r.UserIdentity.Type == "Service" && r.UserIdentity.PrincipalID == "dynamodb.amazonaws.com"
+}
+
+// handler writes the alerts expired in a batch to
+// <prefix>/dt=YYYY-MM-DD/<first sequence number>.jsonl.gz (one object per day
+// of alert ts), then deletes their clips. A retried batch writes the same keys,
+// so a failure can simply be returned.
+func handler(ctx context.Context, e events.DynamoDBEvent) error {
+	days := map[string][]map[string]any{}
+	var clips []string
+	first := ""
+	for _, r := range e.Records {
+		if !expiredByTTL(r) || r.Change.OldImage == nil {
+			continue
+		}
+		if first == "" {
+			first = r.Change.SequenceNumber
+		}
+		item := plainMap(r.Change.OldImage)
+		ts, _ := item["ts"].(string)
+		day := "unknown"
+		if len(ts) >= 10 {
+			day = ts[:10]
+		}
+		days[day] = append(days[day], item)
+		if k, _ := item["s3Key"].(string); k != "" {
+			clips = append(clips, k)
+		}
+	}
+	if len(days) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(days))
+	for day := range days {
+		keys = append(keys, day)
+	}
+	sort.Strings(keys)
+	for _, day := range keys {
+		body, err := jsonlGz(days[day])
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s/dt=%s/%s.jsonl.gz", archivePfx, day, first)
+		_, err = s3c.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:               aws.String(archiveBkt),
+			Key:                  aws.String(key),
+			Body:                 bytes.NewReader(body),
+			ContentType:          aws.String("application/x-ndjson"),
+			ContentEncoding:      aws.String("gzip"),
+			ServerSideEncryption: s3types.ServerSideEncryptionAes256,
+		})
+		if err != nil {
+			return fmt.Errorf("put %s: %w", key, err)
+		}
+		log.Printf("archived %d alerts to s3://%s/%s", len(days[day]), archiveBkt, key)
+	}
+
+	// the alert was the only reference to its clip
+	if audioBucket == "" {
+		return nil
+	}
+	for _, k := range clips {
+		_, err := s3c.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(audioBucket),
+			Key:    aws.String(k),
+		})
+		if err != nil {
+			return fmt.Errorf("delete clip %s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+func jsonlGz(items []map[string]any) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	enc.SetEscapeHTML(false)
+	for _, it := range items {
+		if err := enc.Encode(it); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// plainMap turns a stream image into plain JSON values (the same shape the
+// worker API returns), numbers kept as written.
+func plainMap(m map[string]events.DynamoDBAttributeValue) map[string]any {
+	res := make(map[string]any, len(m))
+	for k, v := range m {
+		res[k] = plain(v)
+	}
+	return res
+}
+
+func plain(v events.DynamoDBAttributeValue) any {
+	switch v.DataType() {
+	case events.DataTypeString:
+		return v.String()
+	case events.DataTypeNumber:
+		return json.Number(v.Number())
+	case events.DataTypeBoolean:
+		return v.Boolean()
+	case events.DataTypeBinary:
+		return v.Binary()
+	case events.DataTypeMap:
+		return plainMap(v.Map())
+	case events.DataTypeList:
+		l := v.List()
+		res := make([]any, len(l))
+		for i, x := range l {
+			res[i] = plain(x)
+		}
+		return res
+	case events.DataTypeStringSet:
+		return v.StringSet()
+	case events.DataTypeNumberSet:
+		ns := v.NumberSet()
+		res := make([]json.Number, len(ns))
+		for i, n := range ns {
+			res[i] = json.Number(n)
+		}
+		return res
+	case events.DataTypeBinarySet:
+		return v.BinarySet()
+	}
+	return nil
+}
+
+func main() { lambda.Start(handler) }
@@ -41,6 +41,11 @@ func handler(ctx context.Context, e events.DynamoDBEvent) error {
 			continue
 		}
 		ni := r.Change.NewImage
+		if origin, ok := ni["origin"]; ok {
+			// synced from an edge gateway that already processed it
+			log.Printf("skip alert from gateway %s: deviceId=%s ts=%s", origin.String(), ni["deviceId"].String(), ni["ts"].String())
+			continue
+		}
 		dev := ni["deviceId"].String()
 		ts := ni["ts"].String()
 		s3k := ni["s3Key"].String()
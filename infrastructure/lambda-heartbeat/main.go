@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbt "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// heartbeat is sent by a sensor every minute or so, independently of alerts,
+// so the backend can tell a quiet sensor from a dead one. Telemetry is optional.
+type heartbeatReq struct {
+	DeviceID  string     `json:"deviceId"`
+	TS        string     `json:"ts"`
+	Telemetry *telemetry `json:"telemetry"`
+}
+
+type telemetry struct {
+	BatteryV      *float64 `json:"batteryV"`
+	TemperatureC  *float64 `json:"temperatureC"`
+	RSSI          *float64 `json:"rssi"`
+	FreeStorageMB *float64 `json:"freeStorageMb"`
+	NoiseFloorDB  *float64 `json:"noiseFloorDb"`
+}
+
+type heartbeatResp struct {
+	OK            bool   `json:"ok"`
+	LastHeartbeat string `json:"lastHeartbeat"`
+}
+
+var (
+	ddb          *dynamodb.Client
+	devicesTbl   string
+	telemetryTbl string
+	telemetryTTL time.Duration
+)
+
+// sensor clocks may drift; a ts further off than this is replaced by server time
+const maxClockSkew = 5 * time.Minute
+
+func init() {
+	devicesTbl = os.Getenv("DEVICES_TABLE")
+	if devicesTbl == "" {
+		log.Fatal("DEVICES_TABLE env is required")
+	}
+	telemetryTbl = os.Getenv("TELEMETRY_TABLE")
+	days, err := strconv.Atoi(os.Getenv("TELEMETRY_TTL_DAYS"))
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	telemetryTTL = time.Duration(days) * 24 * time.Hour
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	ddb = dynamodb.NewFromConfig(cfg)
+}
+
+func handler(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	if req.RequestContext.HTTP.Method != "POST" {
+		return jsonResp(405, map[string]string{"error": "method not allowed"})
+	}
+	var in heartbeatReq
+	if err := json.Unmarshal([]byte(req.Body), &in); err != nil {
+		return jsonResp(400, map[string]string{"error": "invalid json"})
+	}
+	if strings.TrimSpace(in.DeviceID) == "" {
+		return jsonResp(400, map[string]string{"error": "deviceId required"})
+	}
+
+	now := time.Now().UTC()
+	at := now
+	if t, err := time.Parse(time.RFC3339, in.TS); err == nil && t.Sub(now).Abs() <= maxClockSkew {
+		at = t.UTC()
+	}
+	ts := at.Format(time.RFC3339)
+
+	values := in.Telemetry.attributes()
+	update := "SET lastHeartbeat = :hb"
+	exprValues := map[string]ddbt.AttributeValue{
+		":hb": &ddbt.AttributeValueMemberS{Value: ts},
+	}
+	if len(values) > 0 {
+		// latest values for GET /sensors; the history goes to the telemetry table
+		latest := map[string]ddbt.AttributeValue{"ts": &ddbt.AttributeValueMemberS{Value: ts}}
+		for k, v := range values {
+			latest[k] = v
+		}
+		update += ", telemetry = :t"
+		exprValues[":t"] = &ddbt.AttributeValueMemberM{Value: latest}
+	}
+
+	// only moves forward: a delayed, retried heartbeat must not rewind the record
+	_, err := ddb.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(devicesTbl),
+		Key: map[string]ddbt.AttributeValue{
+			"deviceId": &ddbt.AttributeValueMemberS{Value: in.DeviceID},
+		},
+		ConditionExpression:       aws.String("attribute_exists(deviceId) AND (attribute_not_exists(lastHeartbeat) OR lastHeartbeat < :hb)"),
+		UpdateExpression:          aws.String(update),
+		ExpressionAttributeValues: exprValues,
+	})
+	var cond *ddbt.ConditionalCheckFailedException
+	if errors.As(err, &cond) {
+		exists, gerr := deviceExists(ctx, in.DeviceID)
+		if gerr != nil {
+			return jsonResp(500, map[string]string{"error": "ddb get failed: " + gerr.Error()})
+		}
+		if !exists {
+			return jsonResp(404, map[string]string{"error": "unknown device"})
+		}
+		// an equal or newer heartbeat is already stored; the sample still
+		// belongs in the history
+	} else if err != nil {
+		return jsonResp(500, map[string]string{"error": "ddb update failed: " + err.Error()})
+	}
+
+	if len(values) > 0 && telemetryTbl != "" {
+		item := map[string]ddbt.AttributeValue{
+			"deviceId": &ddbt.AttributeValueMemberS{Value: in.DeviceID},
+			"ts":       &ddbt.AttributeValueMemberS{Value: ts},
+			"ttl":      &ddbt.AttributeValueMemberN{Value: strconv.FormatInt(at.Add(telemetryTTL).Unix(), 10)},
+		}
+		for k, v := range values {
+			item[k] = v
+		}
+		_, err = ddb.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(telemetryTbl),
+			Item:      item,
+		})
+		if err != nil {
+			return jsonResp(500, map[string]string{"error": "telemetry put failed: " + err.Error()})
+		}
+	}
+
+	return jsonResp(200, heartbeatResp{OK: true, LastHeartbeat: ts})
+}
+
+// attributes returns the reported values keyed by their DynamoDB attribute names.
+func (t *telemetry) attributes() map[string]ddbt.AttributeValue {
+	if t == nil {
+		return nil
+	}
+	out := map[string]ddbt.AttributeValue{}
+	for name, v := range map[string]*float64{
+		"batteryV":      t.BatteryV,
+		"temperatureC":  t.TemperatureC,
+		"rssi":          t.RSSI,
+		"freeStorageMb": t.FreeStorageMB,
+		"noiseFloorDb":  t.NoiseFloorDB,
+	} {
+		if v == nil {
+			continue
+		}
+		out[name] = &ddbt.AttributeValueMemberN{Value: strconv.FormatFloat(*v, 'f', -1, 64)}
+	}
+	return out
+}
+
+func deviceExists(ctx context.Context, deviceID string) (bool, error) {
+	out, err := ddb.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(devicesTbl),
+		Key: map[string]ddbt.AttributeValue{
+			"deviceId": &ddbt.AttributeValueMemberS{Value: deviceID},
+		},
+		ProjectionExpression: aws.String("deviceId"),
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(out.Item) > 0, nil
+}
+
+func jsonResp(code int, v any) (events.APIGatewayV2HTTPResponse, error) {
+	b, _ := json.Marshal(v)
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: code,
+		Headers: map[string]string{
+			"Content-Type":                "application/json",
+			"Access-Control-Allow-Origin": "*",
+			"Cache-Control":               "no-store",
+		},
+		Body: string(b),
+	}, nil
+}
+
+func main() { lambda.Start(handler) }
@@ -27,8 +27,82 @@ resource "aws_dynamodb_table" "alerts" {
     type = "S"
   }
 
+  attribute {
+    name = "tsDay"
+    type = "S"
+  }
+
+  # time-range reads (GET /alerts without deviceId)
+  global_secondary_index {
+    name            = "byDay"
+    hash_key        = "tsDay"
+    range_key       = "ts"
+    projection_type = "ALL"
+  }
+
+  # set by lambda-alert / the worker (models.Retention); expired items go
+  # through the stream to lambda-archiver
+  ttl {
+    attribute_name = "ttl"
+    enabled        = true
+  }
+
   stream_enabled   = true
-  stream_view_type = "NEW_IMAGE"
+  stream_view_type = "NEW_AND_OLD_IMAGES"
 
   tags = merge(local.tags, { Table = "alerts" })
 }
+
+resource "aws_dynamodb_table" "sources" {
+  name         = "sources"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "id"
+
+  attribute {
+    name = "id"
+    type = "S"
+  }
+
+  attribute {
+    name = "state"
+    type = "S"
+  }
+
+  attribute {
+    name = "updatedAt"
+    type = "S"
+  }
+
+  global_secondary_index {
+    name            = "byState"
+    hash_key        = "state"
+    range_key       = "updatedAt"
+    projection_type = "ALL"
+  }
+
+  tags = merge(local.tags, { Table = "sources" })
+}
+
+resource "aws_dynamodb_table" "telemetry" {
+  name         = "telemetry"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "deviceId"
+  range_key    = "ts"
+
+  attribute {
+    name = "deviceId"
+    type = "S"
+  }
+
+  attribute {
+    name = "ts"
+    type = "S"
+  }
+
+  ttl {
+    attribute_name = "ttl"
+    enabled        = true
+  }
+
+  tags = merge(local.tags, { Table = "telemetry" })
+}
@@ -37,12 +37,17 @@ resource "aws_security_group" "ssh" {
     cidr_blocks = ["0.0.0.0/0"]
   }
 
-  ingress {
-    description = "Backend API"
-    from_port   = 8080
-    to_port     = 8080
-    protocol    = "tcp"
-    cidr_blocks = ["0.0.0.0/0"]
+  # the frontend reaches the API through nginx on port 80 (/api -> localhost:8080);
+  # direct access to 8080 only from explicitly allowed networks
+  dynamic "ingress" {
+    for_each = length(var.api_allowed_cidrs) > 0 ? [1] : []
+    content {
+      description = "Backend API"
+      from_port   = 8080
+      to_port     = 8080
+      protocol    = "tcp"
+      cidr_blocks = var.api_allowed_cidrs
+    }
   }
 
   egress {
@@ -66,11 +71,21 @@ resource "aws_iam_role_policy" "ec2_sqs" {
   role = aws_iam_role.ec2_role.id
   policy = jsonencode({
     Version = "2012-10-17",
-    Statement = [{
-      Effect   = "Allow",
-      Action   = ["sqs:ReceiveMessage", "sqs:DeleteMessage", "sqs:GetQueueAttributes"],
-      Resource = aws_sqs_queue.alerts.arn
-    }]
+    Statement = [
+      {
+        Effect   = "Allow",
+        Action   = ["sqs:ReceiveMessage", "sqs:DeleteMessage", "sqs:GetQueueAttributes", "sqs:SendMessage"],
+        Resource = aws_sqs_queue.alerts.arn
+      },
+      {
+        Effect = "Allow",
+        Action = ["sqs:ReceiveMessage", "sqs:DeleteMessage", "sqs:GetQueueAttributes", "sqs:SendMessage", "sqs:PurgeQueue", "sqs:ChangeMessageVisibility"],
+        Resource = [
+          aws_sqs_queue.alerts_dlq.arn,
+          aws_sqs_queue.alerts_quarantine.arn
+        ]
+      }
+    ]
   })
 }
 
@@ -87,16 +102,33 @@ resource "aws_iam_role_policy" "ec2_ddb" {
         "dynamodb:UpdateItem",
         "dynamodb:Query",
         "dynamodb:Scan",
-        "dynamodb:BatchWriteItem"
+        "dynamodb:BatchGetItem",
+        "dynamodb:BatchWriteItem",
+        "dynamodb:DescribeTable"
       ],
       Resource : [
         aws_dynamodb_table.alerts.arn,
-        aws_dynamodb_table.devices.arn
+        aws_dynamodb_table.devices.arn,
+        aws_dynamodb_table.sources.arn,
+        aws_dynamodb_table.telemetry.arn,
+        "${aws_dynamodb_table.alerts.arn}/index/*",
+        "${aws_dynamodb_table.sources.arn}/index/*"
       ]
     }]
   })
 }
 
+resource "aws_iam_role_policy" "ec2_s3" {
+  name = "${local.project}-ec2-s3"
+  role = aws_iam_role.ec2_role.id
+  policy = jsonencode({
+    Version = "2012-10-17",
+    Statement = [
+      { Effect = "Allow", Action = ["s3:GetObject"], Resource = "${aws_s3_bucket.audio.arn}/*" },
+      { Effect = "Allow", Action = ["s3:ListBucket"], Resource = aws_s3_bucket.audio.arn }
+    ]
+  })
+}
 
 resource "aws_iam_instance_profile" "ec2_profile" {
   name = "${local.project}-ec2-profile"
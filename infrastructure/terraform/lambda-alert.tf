@@ -43,7 +43,8 @@ resource "aws_lambda_function" "alert" {
     variables = {
       ALERTS_TABLE  = aws_dynamodb_table.alerts.name
       AUDIO_BUCKET  = aws_s3_bucket.audio.bucket
-      DEVICES_TABLE = aws_dynamodb_table.devices.name
+      DEVICES_TABLE  = aws_dynamodb_table.devices.name
+      ALERT_TTL_DAYS = var.alert_retention_days
     }
   }
 
@@ -0,0 +1,84 @@
+resource "aws_iam_role" "lambda_archiver" {
+  name = "${local.project}-archiver-role"
+  assume_role_policy = jsonencode({
+    Version   = "2012-10-17",
+    Statement = [{ Effect = "Allow", Action = "sts:AssumeRole", Principal = { Service = "lambda.amazonaws.com" } }]
+  })
+}
+
+resource "aws_iam_role_policy_attachment" "lambda_archiver_logs" {
+  role       = aws_iam_role.lambda_archiver.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_iam_policy" "lambda_archiver_io" {
+  name = "${local.project}-archiver-io"
+  policy = jsonencode({
+    Version = "2012-10-17",
+    Statement = [
+      {
+        Effect = "Allow",
+        Action = [
+          "dynamodb:DescribeStream",
+          "dynamodb:GetRecords",
+          "dynamodb:GetShardIterator",
+          "dynamodb:ListStreams"
+        ],
+        Resource = [
+          aws_dynamodb_table.alerts.stream_arn,
+          "${aws_dynamodb_table.alerts.arn}/stream/*"
+        ]
+      },
+      { Effect = "Allow", Action = ["s3:PutObject"], Resource = ["${aws_s3_bucket.audio.arn}/archive/*"] },
+      { Effect = "Allow", Action = ["s3:DeleteObject"], Resource = ["${aws_s3_bucket.audio.arn}/*"] }
+    ]
+  })
+}
+
+resource "aws_iam_role_policy_attachment" "lambda_archiver_io_attach" {
+  role       = aws_iam_role.lambda_archiver.name
+  policy_arn = aws_iam_policy.lambda_archiver_io.arn
+}
+
+resource "aws_lambda_function" "archiver" {
+  function_name    = "${local.project}-archiver"
+  role             = aws_iam_role.lambda_archiver.arn
+  filename         = "dist_archiver.zip"
+  source_code_hash = filebase64sha256("dist_archiver.zip")
+
+  handler       = "bootstrap"
+  runtime       = "provided.al2023"
+  architectures = ["arm64"]
+  timeout       = 60
+
+  environment {
+    variables = {
+      ARCHIVE_BUCKET = aws_s3_bucket.audio.bucket
+      ARCHIVE_PREFIX = "archive/alerts"
+      AUDIO_BUCKET   = aws_s3_bucket.audio.bucket
+    }
+  }
+
+  tags = local.tags
+}
+
+# only deletions made by DynamoDB TTL, not INSERT/MODIFY or manual deletes
+resource "aws_lambda_event_source_mapping" "alerts_stream_to_archiver" {
+  event_source_arn  = aws_dynamodb_table.alerts.stream_arn
+  function_name     = aws_lambda_function.archiver.arn
+  starting_position = "LATEST"
+  batch_size        = 100
+
+  maximum_batching_window_in_seconds = 60
+
+  filter_criteria {
+    filter {
+      pattern = jsonencode({
+        eventName    = ["REMOVE"]
+        userIdentity = { type = ["Service"], principalId = ["dynamodb.amazonaws.com"] }
+      })
+    }
+  }
+
+  depends_on = [aws_iam_role_policy_attachment.lambda_archiver_io_attach]
+}
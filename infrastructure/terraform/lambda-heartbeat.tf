@@ -0,0 +1,72 @@
+resource "aws_iam_role" "lambda_heartbeat" {
+  name = "${local.project}-heartbeat-role"
+  assume_role_policy = jsonencode({
+    Version   = "2012-10-17",
+    Statement = [{ Effect = "Allow", Action = "sts:AssumeRole", Principal = { Service = "lambda.amazonaws.com" } }]
+  })
+}
+
+resource "aws_iam_role_policy_attachment" "lambda_heartbeat_logs" {
+  role       = aws_iam_role.lambda_heartbeat.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_iam_policy" "lambda_heartbeat_io" {
+  name = "${local.project}-heartbeat-io"
+  policy = jsonencode({
+    Version = "2012-10-17",
+    Statement = [
+      { Effect = "Allow", Action = ["dynamodb:UpdateItem", "dynamodb:GetItem"], Resource = aws_dynamodb_table.devices.arn },
+      { Effect = "Allow", Action = ["dynamodb:PutItem"], Resource = aws_dynamodb_table.telemetry.arn }
+    ]
+  })
+}
+
+resource "aws_iam_role_policy_attachment" "lambda_heartbeat_io_attach" {
+  role       = aws_iam_role.lambda_heartbeat.name
+  policy_arn = aws_iam_policy.lambda_heartbeat_io.arn
+}
+
+resource "aws_lambda_function" "heartbeat" {
+  function_name    = "${local.project}-heartbeat"
+  role             = aws_iam_role.lambda_heartbeat.arn
+  filename         = "dist_heartbeat.zip"
+  source_code_hash = filebase64sha256("dist_heartbeat.zip")
+
+  handler       = "bootstrap"
+  runtime       = "provided.al2023"
+  architectures = ["arm64"]
+  timeout       = 5
+  memory_size   = 128
+
+  environment {
+    variables = {
+      DEVICES_TABLE      = aws_dynamodb_table.devices.name
+      TELEMETRY_TABLE    = aws_dynamodb_table.telemetry.name
+      TELEMETRY_TTL_DAYS = var.telemetry_retention_days
+    }
+  }
+
+  tags = local.tags
+}
+
+resource "aws_apigatewayv2_integration" "heartbeat" {
+  api_id                 = aws_apigatewayv2_api.http.id
+  integration_type       = "AWS_PROXY"
+  integration_uri        = aws_lambda_function.heartbeat.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_route" "heartbeat" {
+  api_id    = aws_apigatewayv2_api.http.id
+  route_key = "POST /heartbeat"
+  target    = "integrations/${aws_apigatewayv2_integration.heartbeat.id}"
+}
+
+resource "aws_lambda_permission" "apigw_invoke_heartbeat" {
+  statement_id  = "AllowAPIGatewayInvokeHeartbeat"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.heartbeat.function_name
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_apigatewayv2_api.http.execution_arn}/*/*"
+}
@@ -1,8 +1,11 @@
 output "api_base_url" { value = aws_apigatewayv2_api.http.api_endpoint }
 output "register_endpoint" { value = "${aws_apigatewayv2_api.http.api_endpoint}/register" }
 output "alert_endpoint" { value = "${aws_apigatewayv2_api.http.api_endpoint}/alert" }
+output "heartbeat_endpoint" { value = "${aws_apigatewayv2_api.http.api_endpoint}/heartbeat" }
 output "audio_bucket" { value = aws_s3_bucket.audio.bucket }
 output "alerts_queue_url" { value = aws_sqs_queue.alerts.url }
+output "alerts_dlq_url" { value = aws_sqs_queue.alerts_dlq.url }
+output "alerts_quarantine_url" { value = aws_sqs_queue.alerts_quarantine.url }
 output "worker_public_ip" {
   value       = aws_instance.worker[0].public_ip
   description = "Public EC2 IPv4"
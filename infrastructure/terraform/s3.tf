@@ -8,6 +8,22 @@ resource "aws_s3_bucket" "audio" {
   tags          = local.tags
 }
 
+resource "aws_s3_bucket_lifecycle_configuration" "audio" {
+  bucket = aws_s3_bucket.audio.id
+
+  rule {
+    id     = "archive-to-ia"
+    status = "Enabled"
+    filter {
+      prefix = "archive/"
+    }
+    transition {
+      days          = var.archive_ia_after_days
+      storage_class = "STANDARD_IA"
+    }
+  }
+}
+
 resource "aws_s3_bucket_public_access_block" "audio" {
   bucket                  = aws_s3_bucket.audio.id
   block_public_acls       = true
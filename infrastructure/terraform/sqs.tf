@@ -6,6 +6,14 @@ resource "aws_sqs_queue" "alerts_dlq" {
   tags                        = local.tags
 }
 
+resource "aws_sqs_queue" "alerts_quarantine" {
+  name                        = "${local.project}-alerts-quarantine.fifo"
+  fifo_queue                  = true
+  content_based_deduplication = true
+  message_retention_seconds   = 1209600
+  tags                        = local.tags
+}
+
 resource "aws_sqs_queue" "alerts" {
   name                        = "${local.project}-alerts.fifo"
   fifo_queue                  = true
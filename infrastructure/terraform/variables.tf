@@ -7,3 +7,27 @@ variable "create_ec2_worker" {
   type    = bool
   default = true
 }
+
+variable "api_allowed_cidrs" {
+  description = "CIDRs allowed to reach the worker API on port 8080 directly (e.g. office VPN); empty = nginx only"
+  type        = list(string)
+  default     = []
+}
+
+variable "alert_retention_days" {
+  description = "TTL set by lambda-alert on new alerts; the worker extends it for alerts in a source (keep equal to retention.unattached_days)"
+  type        = number
+  default     = 30
+}
+
+variable "archive_ia_after_days" {
+  description = "Days after which archived alerts (archive/ prefix) move to STANDARD_IA"
+  type        = number
+  default     = 30
+}
+
+variable "telemetry_retention_days" {
+  description = "How long sensor telemetry samples are kept (DynamoDB TTL)"
+  type        = number
+  default     = 30
+}